@@ -0,0 +1,222 @@
+package dchart2
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// boxstats are the statistics of a box plot: the quartiles, the ends of the whiskers,
+// and the samples beyond them
+type boxstats struct {
+	q1, median, q3 float64
+	low, high      float64
+	outliers       []float64
+}
+
+// ReadTSVSamples reads long-format tab separated values, a label and a value on each line,
+// into a ChartBox with a datum for each label, in order of appearance, holding the values
+// of all the lines with that label as samples. The value of each label is the median.
+// Values are read as in ReadTSV.
+func ReadTSVSamples(r io.Reader) (ChartBox, error) {
+	c, err := readtsv(r, false)
+	return groupsamples(c), err
+}
+
+// ReadTSVSamplesStrict reads long-format tab separated values like ReadTSVSamples,
+// but values that are not numbers are errors (*ParseError)
+func ReadTSVSamplesStrict(r io.Reader) (ChartBox, error) {
+	c, err := readtsv(r, true)
+	if err != nil {
+		return ChartBox{}, err
+	}
+	return groupsamples(c), nil
+}
+
+// ReadCSVSamples reads long-format CSV values into a ChartBox with a datum for each label,
+// like ReadTSVSamples; csvcols selects the label and value columns, as in ReadCSV.
+func ReadCSVSamples(r io.Reader, csvcols string) (ChartBox, error) {
+	c, err := readcsv(r, csvcols, false)
+	return groupsamples(c), err
+}
+
+// ReadCSVSamplesStrict reads long-format CSV values like ReadCSVSamples,
+// but values that are not numbers, and malformed rows, are errors (*ParseError)
+func ReadCSVSamplesStrict(r io.Reader, csvcols string) (ChartBox, error) {
+	c, err := readcsv(r, csvcols, true)
+	if err != nil {
+		return ChartBox{}, err
+	}
+	return groupsamples(c), nil
+}
+
+// groupsamples returns the chart with the data grouped by label: a datum for each label,
+// in order of appearance, with the values of the label as samples, and the median as the value.
+// The chart's range is that of the samples.
+func groupsamples(c ChartBox) ChartBox {
+	var data []NameValue
+	index := map[string]int{}
+	for _, d := range c.Data {
+		i, ok := index[d.Label]
+		if !ok {
+			i = len(data)
+			index[d.Label] = i
+			data = append(data, NameValue{Label: d.Label, Note: d.Note, X: d.X})
+		}
+		data[i].Samples = append(data[i].Samples, d.Value)
+	}
+	c.Minvalue, c.Maxvalue = largest, smallest
+	for i := range data {
+		s := data[i].Samples
+		sort.Float64s(s)
+		data[i].Value = quantile(s, 0.5)
+		c.Minvalue = math.Min(c.Minvalue, s[0])
+		c.Maxvalue = math.Max(c.Maxvalue, s[len(s)-1])
+	}
+	c.Data = data
+	c.Series = nil
+	c.XMinvalue, c.XMaxvalue = xminmax(data)
+	return c
+}
+
+// boxsummary returns the box plot statistics of the samples. The whiskers extend to
+// the smallest and largest samples within 1.5 times the interquartile range of the box,
+// and the samples beyond are outliers; with minmax, the whiskers extend to the
+// smallest and largest samples, and there are no outliers.
+func boxsummary(samples []float64, minmax bool) boxstats {
+	s := append([]float64(nil), samples...)
+	sort.Float64s(s)
+	b := boxstats{q1: quantile(s, 0.25), median: quantile(s, 0.5), q3: quantile(s, 0.75)}
+	b.low, b.high = s[0], s[len(s)-1]
+	if minmax {
+		return b
+	}
+	fence := 1.5 * (b.q3 - b.q1)
+	b.low, b.high = b.q1, b.q3
+	for _, v := range s {
+		switch {
+		case v < b.q1-fence || v > b.q3+fence:
+			b.outliers = append(b.outliers, v)
+		case v < b.low:
+			b.low = v
+		case v > b.high:
+			b.high = v
+		}
+	}
+	return b
+}
+
+// boxvalue returns the description of box plot statistics
+func (c *ChartBox) boxvalue(b boxstats) string {
+	f := c.DataFormat
+	return fmt.Sprintf("median "+f+" quartiles "+f+" to "+f+" whiskers "+f+" to "+f, b.median, b.q1, b.q3, b.low, b.high)
+}
+
+// boxsamples returns the samples of a datum, or if there are none, its value
+func boxsamples(d NameValue) []float64 {
+	if len(d.Samples) == 0 {
+		return []float64{d.Value}
+	}
+	return d.Samples
+}
+
+// BoxPlot makes vertical box plots of the samples of each label (see ReadTSVSamples):
+// a box of the specified width from the first to the third quartile, with a line at the median,
+// whiskers, and dots for outliers. The whiskers extend to the smallest and largest samples
+// within 1.5 times the interquartile range of the box, or with minmax,
+// to the smallest and largest samples.
+func (c *ChartBox) BoxPlot(deck Renderer, size float64, minmax bool) error {
+	if err := c.need("box plot", 1); err != nil {
+		return err
+	}
+	theme := c.theme()
+	lw := c.TextSize / 8
+	for i, d := range c.Data {
+		b := boxsummary(boxsamples(d), minmax)
+		x := c.xpos(i)
+		y1, ym, y3 := c.ypos(b.q1), c.ypos(b.median), c.ypos(b.q3)
+		startitem(deck, -1, "", d.Label, c.boxvalue(b), d.Note)
+		deck.Line(x, c.ypos(b.low), x, y1, lw, theme.Line, c.Opacity)
+		deck.Line(x, y3, x, c.ypos(b.high), lw, theme.Line, c.Opacity)
+		deck.Line(x-(size/4), c.ypos(b.low), x+(size/4), c.ypos(b.low), lw, theme.Line, c.Opacity)
+		deck.Line(x-(size/4), c.ypos(b.high), x+(size/4), c.ypos(b.high), lw, theme.Line, c.Opacity)
+		deck.Rect(x, (y1+y3)/2, size, math.Max(y3-y1, lw), c.DataColor, c.Opacity)
+		deck.Line(x-(size/2), ym, x+(size/2), ym, lw*2, theme.Line, c.Opacity)
+		for _, v := range b.outliers {
+			deck.Circle(x, c.ypos(v), c.TextSize*0.6, c.ValueColor, c.Opacity)
+		}
+		enditem(deck)
+	}
+	return nil
+}
+
+// HBoxPlot makes horizontal box plots of the samples of each label, like BoxPlot,
+// with a row for each label, labeled on the left, beginning at the top, at the specified spacing
+func (c *ChartBox) HBoxPlot(deck Renderer, size, linespacing float64, minmax bool) error {
+	if err := c.need("horizontal box plot", 1); err != nil {
+		return err
+	}
+	theme := c.theme()
+	textsize := c.TextSize
+	lw := textsize / 8
+	y := c.Top
+	for _, d := range c.Data {
+		b := boxsummary(boxsamples(d), minmax)
+		x1, xm, x3 := c.vmap(b.q1, c.Left, c.Right), c.vmap(b.median, c.Left, c.Right), c.vmap(b.q3, c.Left, c.Right)
+		xl, xh := c.vmap(b.low, c.Left, c.Right), c.vmap(b.high, c.Left, c.Right)
+		deck.TextEnd(c.Left-textsize, y-textsize/3, d.Label, "sans", textsize, c.LabelColor)
+		startitem(deck, -1, "", d.Label, c.boxvalue(b), d.Note)
+		deck.Line(xl, y, x1, y, lw, theme.Line, c.Opacity)
+		deck.Line(x3, y, xh, y, lw, theme.Line, c.Opacity)
+		deck.Line(xl, y-(size/4), xl, y+(size/4), lw, theme.Line, c.Opacity)
+		deck.Line(xh, y-(size/4), xh, y+(size/4), lw, theme.Line, c.Opacity)
+		deck.Rect((x1+x3)/2, y, math.Max(x3-x1, lw), size, c.DataColor, c.Opacity)
+		deck.Line(xm, y-(size/2), xm, y+(size/2), lw*2, theme.Line, c.Opacity)
+		for _, v := range b.outliers {
+			deck.Circle(c.vmap(v, c.Left, c.Right), y, textsize*0.6, c.ValueColor, c.Opacity)
+		}
+		enditem(deck)
+		y -= linespacing
+	}
+	return nil
+}
+
+// whiskers reports whether the whiskers of box plots extend to the minimum and maximum
+// ("minmax"), rather than 1.5 times the interquartile range ("iqr", the default)
+func (s *Settings) whiskers() (bool, error) {
+	switch s.Whiskers {
+	case "", "iqr":
+		return false, nil
+	case "minmax":
+		return true, nil
+	}
+	return false, &ArgumentError{Name: s.Whiskers, Reason: "unknown whiskers (use iqr or minmax)"}
+}
+
+// hboxplot makes horizontal box plots, with an axis of the values below the rows
+func (s *Settings) hboxplot(deck Renderer, c *ChartBox) error {
+	f := s.Flags
+	m := s.Measures
+	size := m.BarWidth
+	if size == 0 {
+		size = m.LineSpacing * 0.6
+	}
+	minmax, err := s.whiskers()
+	if err != nil {
+		return err
+	}
+	if err := c.HBoxPlot(deck, size, m.LineSpacing, minmax); err != nil {
+		return err
+	}
+	if f.ShowAxis {
+		axis := *c
+		axis.XValue, axis.XTime = true, false
+		axis.XMinvalue, axis.XMaxvalue = c.ydomain()
+		axis.Top = c.Top + (m.LineSpacing / 2)
+		axis.Bottom = c.Top - (float64(len(c.Data)) * m.LineSpacing)
+		min, max, step := xtickrange(axis.XMinvalue, axis.XMaxvalue, 5)
+		axis.XAxis(deck, min, max, step, f.ShowGrid)
+	}
+	return nil
+}
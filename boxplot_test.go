@@ -0,0 +1,75 @@
+package dchart2
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const sampledata = "a\t1\na\t2\na\t3\na\t20\nb\t-5\nb\t5\nc\t7\n"
+
+func TestReadTSVSamples(t *testing.T) {
+	c, err := ReadTSVSamples(strings.NewReader(sampledata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Data) != 3 || len(c.Data[0].Samples) != 4 {
+		t.Fatalf("got %+v", c.Data)
+	}
+	if c.Data[0].Value != 2.5 || c.Minvalue != -5 || c.Maxvalue != 20 {
+		t.Errorf("got median %v, range %v to %v", c.Data[0].Value, c.Minvalue, c.Maxvalue)
+	}
+}
+
+func TestReadSamplesStrict(t *testing.T) {
+	var perr *ParseError
+	if _, err := ReadTSVSamplesStrict(strings.NewReader("a\t1\na\tone\n")); !errors.As(err, &perr) || perr.Line != 2 {
+		t.Errorf("got %v, want a ParseError at line 2", err)
+	}
+	if _, err := ReadCSVSamplesStrict(strings.NewReader("name,value\na,1\na,one\n"), ""); !errors.As(err, &perr) {
+		t.Errorf("got %v, want a ParseError", err)
+	}
+	if _, err := ReadTSVSamplesStrict(strings.NewReader(sampledata)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBoxPlot(t *testing.T) {
+	c, err := ReadTSVSamples(strings.NewReader(sampledata))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, minmax := range []bool{false, true} {
+		r := &recorder{}
+		if err := c.BoxPlot(r, 2, minmax); err != nil {
+			t.Fatal(err)
+		}
+		if n := r.count("rect"); n != 3 {
+			t.Errorf("got %d boxes, want 3", n)
+		}
+		r.checkfinite(t)
+		r = &recorder{}
+		if err := c.HBoxPlot(r, 2, 5, minmax); err != nil {
+			t.Fatal(err)
+		}
+		r.checkfinite(t)
+	}
+}
+
+func TestWhiskers(t *testing.T) {
+	s := NewChart("box", 0, 0, 0, 0)
+	for _, w := range []string{"", "iqr", "minmax"} {
+		s.Attributes.Whiskers = w
+		if err := s.GenerateChart(&recorder{}, strings.NewReader(sampledata)); err != nil {
+			t.Errorf("%q: %v", w, err)
+		}
+	}
+	var aerr *ArgumentError
+	for _, box := range []bool{true, false} {
+		s.Flags.ShowBox, s.Flags.ShowHBox = box, !box
+		s.Attributes.Whiskers = "tukey"
+		if err := s.GenerateChart(&recorder{}, strings.NewReader(sampledata)); !errors.As(err, &aerr) {
+			t.Errorf("whiskers tukey: got %v, want an ArgumentError", err)
+		}
+	}
+}
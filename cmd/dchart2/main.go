@@ -3,21 +3,64 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/ajstarks/dchart2"
 	"github.com/ajstarks/deck/generate"
 )
 
-func cmdflags() dchart2.Settings {
+// output specifies where and how charts are written
+type output struct {
+	file     string
+	format   string
+	pagesize string
+	dpi      float64
+	ansi     bool
+	spec     string
+	layout   dchart2.Layout
+}
+
+// chart is a chart to draw: its settings, data files (standard input if none),
+// and title in a layout
+type chart struct {
+	settings dchart2.Settings
+	files    []string
+	title    string
+}
+
+// isterminal reports whether the file is a terminal
+func isterminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func cmdflags() (*dchart2.Settings, *output) {
 	var chart dchart2.Settings
+	out := output{layout: dchart2.NewLayout("", 0, 0)}
+
+	// Output
+	flag.StringVar(&out.file, "o", "", "output file (default standard output)")
+	flag.StringVar(&out.spec, "spec", "", "chart specification file (JSON or YAML); flags override the file")
+
+	// Layout
+	flag.StringVar(&out.layout.Arrange, "layout", "", "arrange the charts in a grid, rows or columns, one chart per file")
+	flag.IntVar(&out.layout.Columns, "cols", 0, "number of columns in a grid layout (default: about as many as rows)")
+	flag.Float64Var(&out.layout.Gutter, "gutter", 10, "space between charts in a layout")
+	flag.StringVar(&out.layout.Title, "ltitle", "", "title of a layout")
+	flag.StringVar(&out.format, "format", "", "output format (deck, svg, html, png, pdf, term), default from the output file extension, term on a terminal, or deck")
+	flag.StringVar(&out.pagesize, "pagesize", "", "page size (width,height), for example 1024,768; columns,rows for term")
+	flag.Float64Var(&out.dpi, "dpi", 96, "resolution of png output (dots per inch)")
+	flag.BoolVar(&out.ansi, "ansi", isterminal(os.Stdout), "use ANSI colors in term output")
 
 	// Measures
 	flag.Float64Var(&chart.Measures.TextSize, "textsize", 1.5, "text size")
 	flag.Float64Var(&chart.Left, "left", -1, "left margin")
 	flag.Float64Var(&chart.Right, "right", 90.0, "right margin")
-	flag.Float64Var(&chart.Top, "top", 80.0, "top of the plot")
-	flag.Float64Var(&chart.Bottom, "bottom", 30.0, "bottom of the plot")
+	flag.Float64Var(&chart.Top, "top", 90.0, "top of the plot")
+	flag.Float64Var(&chart.Bottom, "bottom", 50.0, "bottom of the plot")
 	flag.Float64Var(&chart.LineSpacing, "ls", 2.4, "ls")
 	flag.Float64Var(&chart.BarWidth, "barwidth", 0, "barwidth")
 	flag.Float64Var(&chart.UserMin, "min", -1, "minimum")
@@ -26,21 +69,34 @@ func cmdflags() dchart2.Settings {
 	flag.Float64Var(&chart.PWidth, "pwidth", chart.Measures.TextSize*3, "width of the pmap/donut/radial")
 	flag.Float64Var(&chart.LineWidth, "linewidth", 0.2, "width of line for line charts")
 	flag.Float64Var(&chart.VolumeOpacity, "volop", 50, "volume opacity")
+	flag.Float64Var(&chart.VolumePanel, "volpanel", 0, "height of the volume panel of candlestick charts (percent of the chart, 0 for none)")
 	flag.Float64Var(&chart.XLabelRotation, "xlabrot", 0, "xlabel rotation (degrees)")
 	flag.IntVar(&chart.XLabelInterval, "xlabel", 1, "x axis label interval (show every n labels, 0 to show no labels)")
 	flag.IntVar(&chart.PMapLength, "pmlen", 20, "pmap label length")
 
 	// Flags (On/Off)
 	flag.BoolVar(&chart.ShowBar, "bar", true, "show a bar chart")
+	flag.BoolVar(&chart.ShowStackedBar, "stack", false, "show a stacked bar chart")
+	flag.BoolVar(&chart.ShowGroupedBar, "group", false, "show a grouped bar chart")
 	flag.BoolVar(&chart.ShowHDot, "dot", false, "show a dot chart")
+	flag.BoolVar(&chart.ShowVDot, "vdot", false, "show a vertical dot chart")
 	flag.BoolVar(&chart.ShowVolume, "vol", false, "show a volume chart")
 	flag.BoolVar(&chart.ShowDonut, "donut", false, "show a donut chart")
 	flag.BoolVar(&chart.ShowPMap, "pmap", false, "show a proportional map")
 	flag.BoolVar(&chart.ShowLine, "line", false, "show a line chart")
 	flag.BoolVar(&chart.ShowHBar, "hbar", false, "show a horizontal bar chart")
+	flag.BoolVar(&chart.ShowStackedHBar, "hstack", false, "show a stacked horizontal bar chart")
+	flag.BoolVar(&chart.ShowPctHBar, "hpct", false, "show a 100% stacked horizontal bar chart")
 	flag.BoolVar(&chart.ShowValues, "val", true, "show data values")
 	flag.BoolVar(&chart.ShowAxis, "yaxis", false, "show y axis")
 	flag.BoolVar(&chart.ShowSlope, "slope", false, "show a slope graph")
+	flag.BoolVar(&chart.ShowHistogram, "hist", false, "show a histogram of the values (one per line, or a CSV column)")
+	flag.BoolVar(&chart.ShowDensity, "density", false, "show a density curve over a histogram")
+	flag.BoolVar(&chart.ShowBox, "box", false, "show box plots of the values of each label (long-format data)")
+	flag.BoolVar(&chart.ShowHBox, "hbox", false, "show horizontal box plots of the values of each label (long-format data)")
+	flag.BoolVar(&chart.ShowHeatmap, "heatmap", false, "show a heatmap of a matrix (wide data, or long-format with -csvcol row,col,value)")
+	flag.BoolVar(&chart.ShowCandle, "candle", false, "show a candlestick chart (Date,Open,High,Low,Close[,Volume] columns)")
+	flag.BoolVar(&chart.ShowOHLC, "ohlc", false, "show an open-high-low-close chart (Date,Open,High,Low,Close[,Volume] columns)")
 	flag.BoolVar(&chart.ShowTitle, "title", true, "show title")
 	flag.BoolVar(&chart.ShowGrid, "grid", false, "show y axis grid")
 	flag.BoolVar(&chart.ShowScatter, "scatter", false, "show scatter chart")
@@ -52,50 +108,259 @@ func cmdflags() dchart2.Settings {
 	flag.BoolVar(&chart.ShowRegressionLine, "rline", false, "show regression line")
 	flag.BoolVar(&chart.ShowXLast, "xlast", false, "show the last label")
 	flag.BoolVar(&chart.ShowXstagger, "xstagger", false, "stagger x axis labels")
+	flag.BoolVar(&chart.XValue, "xvalue", false, "place data at the numeric value of the labels")
+	flag.BoolVar(&chart.XTime, "xtime", false, "place data at the date or time of the labels")
 	flag.BoolVar(&chart.FullDeck, "fulldeck", true, "generate full markup")
 	flag.BoolVar(&chart.DataMinimum, "dmin", false, "zero minimum")
 	flag.BoolVar(&chart.ReadCSV, "csv", false, "read CSV data")
 	flag.BoolVar(&chart.ShowWBar, "wbar", false, "show word bar chart")
 	flag.BoolVar(&chart.ShowPercentage, "pct", false, "show computed percentages with values")
 	flag.BoolVar(&chart.SolidPMap, "solidpmap", false, "solid pmap colors")
+	flag.BoolVar(&chart.FreeScale, "freey", false, "small multiples have their own y scales")
+	flag.BoolVar(&chart.Strict, "strict", false, "values that are not numbers are errors")
 
 	// Attributes
 	flag.StringVar(&chart.ChartTitle, "chartitle", "", "specify the title (overiding title in the data)")
 	flag.StringVar(&chart.CSVCols, "csvcol", "", "label,value from the CSV header")
+	flag.StringVar(&chart.Bins, "bins", "sturges", "histogram bins: sturges, fd (Freedman-Diaconis), width:w or edges:e1,e2,...")
+	flag.StringVar(&chart.FacetColumn, "facet", "", "make small multiples from CSV data, a chart for each value of the named column")
 	flag.StringVar(&chart.ValuePosition, "valpos", "t", "value position (t=top, b=bottom, m=middle)")
-	flag.StringVar(&chart.LabelColor, "lcolor", "rgb(75,75,75)", "label color")
-	flag.StringVar(&chart.DataColor, "color", "lightsteelblue", "data color")
-	flag.StringVar(&chart.ValueColor, "vcolor", "rgb(127,0,0)", "value color")
+	flag.StringVar(&chart.Theme, "theme", "light", "color theme (light, dark, print, high-contrast)")
+	flag.StringVar(&chart.Palette, "palette", "", "palette of series and std colors, for example tableau10, set2, blues or viridis (default from the theme)")
+	flag.StringVar(&chart.LabelColor, "lcolor", "", "label color (default from the theme)")
+	flag.StringVar(&chart.DataColor, "color", "", "data color, std or a palette name for a color per value (default from the theme)")
+	flag.StringVar(&chart.ValueColor, "vcolor", "", "value color (default from the theme)")
+	flag.StringVar(&chart.UpColor, "upcolor", "", "color of rising prices in candlestick charts (default from the theme)")
+	flag.StringVar(&chart.DownColor, "downcolor", "", "color of falling prices in candlestick charts (default from the theme)")
 	flag.StringVar(&chart.RegressionLineColor, "rlcolor", "rgb(127,0,0)", "regression line color")
 	flag.StringVar(&chart.FrameColor, "framecolor", "rgb(127,127,127)", "framecolor")
-	flag.StringVar(&chart.BackgroundColor, "bgcolor", "white", "background color")
+	flag.StringVar(&chart.BackgroundColor, "bgcolor", "", "background color (default from the theme)")
 	flag.StringVar(&chart.DataFmt, "datafmt", "%.1f", "data format")
 	flag.StringVar(&chart.YAxisR, "yrange", "", "y-axis range (min,max,step)")
+	flag.StringVar(&chart.YScale, "yscale", "linear", "y scale (linear, log10, log2, symlog)")
+	flag.StringVar(&chart.Whiskers, "whiskers", "iqr", "box plot whiskers: iqr (1.5 IQR, with outliers) or minmax")
+	flag.StringVar(&chart.XAxisR, "xrange", "", "x-axis range (min,max,step), with -xvalue")
+	flag.StringVar(&chart.XTimeUnit, "xtimeunit", "", "time axis label unit (day, week, month, quarter, year), with optional count (6month)")
+	flag.StringVar(&chart.XTimeFormat, "xtimefmt", "", "time axis label format (Go time layout, for example \"Jan 2006\")")
 	flag.StringVar(&chart.HLine, "hline", "", "horizontal line value,label")
+	flag.StringVar(&chart.Legend, "legend", "", "legend placement (right, bottom, top-left, top-right, bottom-left, bottom-right)")
+	flag.StringVar(&chart.LegendFlow, "legendflow", "", "legend flow (horizontal, vertical)")
+	flag.StringVar(&chart.LegendSwatch, "swatch", "block", "legend swatch (block, line, dot)")
 	flag.StringVar(&chart.NoteLocation, "noteloc", "c", "note location (c-center, r-right aligned, l-left aligned)")
-	flag.StringVar(&chart.DataCondition, "datacond", "", "data condition: low,high,color")
+	flag.StringVar(&chart.DataCondition, "datacond", "", "data condition: low,high,color; low,high,color:color... or low,high,palette for a gradient; low,mid,high,colors for a diverging gradient; several separated by ;")
+	flag.StringVar(&chart.ColorBar, "colorbar", "", "show a color bar of the data condition (right, bottom, or none; heatmaps default to right)")
 	flag.Parse()
 
-	return chart
+	return &chart, &out
+}
+
+// charts returns the charts to draw: those of the specification file, if any,
+// and the files named on the command line (a chart for each file in a layout).
+// The settings and layout are bound to the flags, so parsing the command line
+// again after applying the specification makes the flags override it.
+func charts(settings *dchart2.Settings, out *output) ([]chart, error) {
+	if len(out.spec) == 0 {
+		return filecharts(*settings, flag.Args(), len(out.layout.Arrange) > 0), nil
+	}
+	f, err := os.Open(out.spec)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := dchart2.ReadSpec(f)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", out.spec, err)
+	}
+	if _, err := spec.ApplyLayout(&out.layout); err != nil {
+		return nil, fmt.Errorf("%s: %v", out.spec, err)
+	}
+	flag.CommandLine.Parse(os.Args[1:])
+
+	defaults := *settings
+	dir := filepath.Dir(out.spec)
+	var list []chart
+	apply := func(name string, specs ...dchart2.ChartSpec) (dchart2.Settings, error) {
+		*settings = defaults
+		for _, cs := range specs {
+			if err := cs.Apply(settings); err != nil {
+				return *settings, fmt.Errorf("%s: %s%v", out.spec, name, err)
+			}
+		}
+		flag.CommandLine.Parse(os.Args[1:])
+		return *settings, nil
+	}
+	for i, cs := range spec.Charts {
+		s, err := apply(fmt.Sprintf("charts[%d]: ", i), spec.ChartSpec, cs)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, chart{settings: s, files: specfiles(dir, cs.Files), title: cs.Title})
+	}
+	files := append(specfiles(dir, spec.Files), flag.Args()...)
+	if len(files) > 0 || len(spec.Charts) == 0 {
+		s, err := apply("", spec.ChartSpec)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, filecharts(s, files, len(out.layout.Arrange) > 0)...)
+	}
+	return list, nil
+}
+
+// filecharts returns a chart of the files with the settings, or a chart for each file if split
+func filecharts(settings dchart2.Settings, files []string, split bool) []chart {
+	if !split || len(files) < 2 {
+		return []chart{{settings: settings, files: files}}
+	}
+	list := make([]chart, len(files))
+	for i, f := range files {
+		list[i] = chart{settings: settings, files: []string{f}}
+	}
+	return list
+}
+
+// specfiles returns the file names of a specification, relative to its directory
+func specfiles(dir string, files []string) []string {
+	names := make([]string, len(files))
+	for i, f := range files {
+		if filepath.IsAbs(f) {
+			names[i] = f
+		} else {
+			names[i] = filepath.Join(dir, f)
+		}
+	}
+	return names
+}
+
+// document makes the document for the output format,
+// using the output file extension if the format is not specified
+func document(w io.Writer, out *output) (dchart2.Document, error) {
+	format := out.format
+	if len(format) == 0 {
+		format = strings.TrimPrefix(filepath.Ext(out.file), ".")
+		if len(out.file) == 0 && isterminal(os.Stdout) {
+			format = "term"
+		}
+	}
+	var width, height float64
+	if len(out.pagesize) > 0 {
+		if n, err := fmt.Sscanf(out.pagesize, "%f,%f", &width, &height); n != 2 || err != nil {
+			return nil, fmt.Errorf("%s: bad page size (use width,height)", out.pagesize)
+		}
+	}
+	switch format {
+	case "", "deck", "xml":
+		return generate.NewSlides(w, int(width), int(height)), nil
+	case "svg":
+		return dchart2.NewSVG(w, width, height), nil
+	case "html", "htm":
+		return dchart2.NewHTML(w, width, height), nil
+	case "png":
+		return dchart2.NewPNG(w, int(width), int(height), out.dpi), nil
+	case "pdf":
+		return dchart2.NewPDF(w, width, height), nil
+	case "term", "txt", "text":
+		return dchart2.NewTerminal(w, int(width), int(height), out.ansi), nil
+	}
+	return nil, fmt.Errorf("%s: unknown output format", format)
 }
+
 func main() {
-	settings := cmdflags()
-	deck := generate.NewSlides(os.Stdout, 0, 0)
-	deck.StartDeck()
-	deck.StartSlide()
-	if len(flag.Args()) > 0 {
-		for _, file := range flag.Args() {
+	settings, out := cmdflags()
+	list, err := charts(settings, out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	// place the charts in the cells of the layout, before writing anything
+	layout := out.layout
+	var cells []dchart2.Cell
+	if len(layout.Arrange) > 0 {
+		layout.Titles = make([]string, len(list))
+		for i, c := range list {
+			layout.Titles[i] = c.title
+		}
+		cells, err = layout.Cells(len(list))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		for i, cell := range cells {
+			m := &list[i].settings.Measures
+			m.Left, m.Right, m.Top, m.Bottom = cell.Left, cell.Right, cell.Top, cell.Bottom
+		}
+	}
+	dest := os.Stdout
+	if len(out.file) > 0 {
+		f, err := os.Create(out.file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		dest = f
+	}
+	deck, err := document(dest, out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	first := list[0].settings
+	theme, err := dchart2.NewTheme(first.Theme)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	background := theme.Background
+	if len(first.BackgroundColor) > 0 {
+		background = first.BackgroundColor
+	}
+	// only deck markup may be a fragment, without the deck and slide
+	_, markup := deck.(*generate.Deck)
+	full := first.FullDeck || !markup
+	if full {
+		deck.StartDeck()
+		deck.StartSlide(background)
+	}
+	if len(cells) > 0 {
+		layout.DrawTitles(deck, cells, theme.Title)
+	}
+	// report chart and file errors, and finish the document
+	status := 0
+	for _, c := range list {
+		if len(c.files) == 0 {
+			if err := c.settings.GenerateChart(deck, os.Stdin); err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				status = 1
+			}
+			continue
+		}
+		for _, file := range c.files {
 			r, err := os.Open(file)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "%v\n", err)
-				os.Exit(1)
+				status = 1
+				continue
+			}
+			if err := c.settings.GenerateChart(deck, r); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+				status = 1
 			}
-			settings.GenerateChart(deck, r)
 			r.Close()
 		}
-	} else {
-		settings.GenerateChart(deck, os.Stdin)
 	}
-	deck.EndSlide()
-	deck.EndDeck()
+	if full {
+		deck.EndSlide()
+		deck.EndDeck()
+	}
+	if e, ok := deck.(interface{ Err() error }); ok && e.Err() != nil { // renderers that write at the end
+		fmt.Fprintf(os.Stderr, "%v\n", e.Err())
+		status = 1
+	}
+	if dest != os.Stdout { // os.Exit skips deferred calls
+		if err := dest.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			status = 1
+		}
+	}
+	os.Exit(status)
 }
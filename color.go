@@ -0,0 +1,53 @@
+package dchart2
+
+import (
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/colornames"
+)
+
+// parsecolor converts a color name, rgb(r,g,b), or #rrggbb to RGBA with
+// the specified alpha (0-1), returning gray for unknown colors
+func parsecolor(s string, alpha float64) color.RGBA {
+	c := color.RGBA{127, 127, 127, 255}
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case strings.HasPrefix(s, "rgb(") && strings.HasSuffix(s, ")"):
+		v := strings.Split(s[4:len(s)-1], ",")
+		if len(v) >= 3 {
+			c.R = colorbyte(v[0])
+			c.G = colorbyte(v[1])
+			c.B = colorbyte(v[2])
+		}
+	case strings.HasPrefix(s, "#") && len(s) == 7:
+		if n, err := strconv.ParseUint(s[1:], 16, 32); err == nil {
+			c = color.RGBA{uint8(n >> 16), uint8(n >> 8), uint8(n), 255}
+		}
+	default:
+		if named, ok := colornames.Map[s]; ok {
+			c = named
+		}
+	}
+	// premultiply by alpha
+	a := math.Max(0, math.Min(alpha, 1))
+	return color.RGBA{uint8(float64(c.R) * a), uint8(float64(c.G) * a), uint8(float64(c.B) * a), uint8(255 * a)}
+}
+
+// iscolor reports whether s is a color: rgb(r,g,b), #rrggbb or a color name
+func iscolor(s string) bool {
+	s = strings.ToLower(strings.TrimSpace(s))
+	_, named := colornames.Map[s]
+	return named || (strings.HasPrefix(s, "rgb(") && strings.HasSuffix(s, ")")) || (strings.HasPrefix(s, "#") && len(s) == 7)
+}
+
+// colorbyte parses a color component (0-255)
+func colorbyte(s string) uint8 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0
+	}
+	return uint8(math.Max(0, math.Min(v, 255)))
+}
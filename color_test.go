@@ -0,0 +1,31 @@
+package dchart2
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		s    string
+		want color.RGBA
+	}{
+		{"red", color.RGBA{255, 0, 0, 255}},
+		{"rgb(0,128,255)", color.RGBA{0, 128, 255, 255}},
+		{"#00ff00", color.RGBA{0, 255, 0, 255}},
+		{"launch day", color.RGBA{127, 127, 127, 255}},
+	}
+	for _, test := range tests {
+		if got := parsecolor(test.s, 1); got != test.want {
+			t.Errorf("parsecolor(%q) = %v, want %v", test.s, got, test.want)
+		}
+	}
+}
+
+func TestIsColor(t *testing.T) {
+	for s, want := range map[string]bool{"red": true, "rgb(1,2,3)": true, "#123456": true, "launch day": false, "": false} {
+		if got := iscolor(s); got != want {
+			t.Errorf("iscolor(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
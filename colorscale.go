@@ -0,0 +1,266 @@
+package dchart2
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ColorScale maps data values to colors
+type ColorScale interface {
+	// Color returns the color of a value, reporting whether the scale colors it
+	Color(v float64) (string, bool)
+	// Stops returns the values where the colors change, in increasing order
+	Stops() []float64
+}
+
+// ColorBand colors the values from Min to Max with a single color, Fill
+type ColorBand struct {
+	Min, Max float64
+	Fill     string
+}
+
+// ColorStop is a color at a value in a gradient
+type ColorStop struct {
+	Value float64
+	Color string
+}
+
+// GradientScale colors values by interpolating between the colors of its stops,
+// which are in increasing order of value. Values beyond the first or last stop
+// take the color of that stop.
+type GradientScale []ColorStop
+
+// ColorScales colors a value with the first scale that colors it,
+// for example with threshold bands
+type ColorScales []ColorScale
+
+// NewColorScale parses a color scale: one or more conditions separated by semicolons.
+// A condition is low,high,color, coloring the values between low and high,
+// or low,high,colors, with colors separated by colons or a palette name,
+// for a gradient evenly spaced from low to high, or low,mid,high,colors
+// for a diverging gradient, with the first half of the colors from low to mid,
+// and the second half from mid to high. For example:
+//
+//	0,10,red                          values from 0 to 10 are red
+//	0,100,white:steelblue             a gradient from white to steelblue
+//	-20,0,20,rdbu                     diverging around zero
+//	0,50,red;50,80,gold;80,100,green  threshold bands
+//
+// An empty string is no scale (nil).
+func NewColorScale(s string) (ColorScale, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	var scales ColorScales
+	for _, cond := range strings.Split(s, ";") {
+		cs := strings.Split(cond, ",")
+		if len(cs) != 3 && len(cs) != 4 {
+			return nil, &ConditionError{Condition: cond}
+		}
+		n := len(cs) - 1
+		values := make([]float64, n)
+		for i := range values {
+			v, err := strconv.ParseFloat(strings.TrimSpace(cs[i]), 64)
+			if err != nil {
+				return nil, &ConditionError{Condition: cond, Reason: cs[i] + " is not a number"}
+			}
+			values[i] = v
+		}
+		if !sort.Float64sAreSorted(values) {
+			return nil, &ConditionError{Condition: cond, Reason: "values out of order"}
+		}
+		colors := strings.Split(strings.TrimSpace(cs[n]), ":")
+		if p, ok := palettes[strings.ToLower(colors[0])]; ok && len(colors) == 1 {
+			colors = p
+		}
+		switch {
+		case len(colors) == 1 && n == 2:
+			scales = append(scales, ColorBand{Min: values[0], Max: values[1], Fill: colors[0]})
+		case len(colors) == 1:
+			return nil, &ConditionError{Condition: cond, Reason: "diverging scales need several colors"}
+		case n == 2:
+			scales = append(scales, GradientScale(spreadstops(colors, values[0], values[1])))
+		default: // the middle of the colors is at mid
+			stops := spreadstops(colors, 0, 1)
+			for i, st := range stops {
+				if st.Value <= 0.5 {
+					stops[i].Value = MapRange(st.Value, 0, 0.5, values[0], values[1])
+				} else {
+					stops[i].Value = MapRange(st.Value, 0.5, 1, values[1], values[2])
+				}
+			}
+			if k := len(colors) / 2; len(colors)%2 == 0 { // a stop at mid, between the middle colors
+				mid := ColorStop{Value: values[1], Color: mixcolor(colors[k-1], colors[k], 0.5)}
+				stops = append(stops[:k], append([]ColorStop{mid}, stops[k:]...)...)
+			}
+			scales = append(scales, GradientScale(stops))
+		}
+	}
+	if len(scales) == 1 {
+		return scales[0], nil
+	}
+	return scales, nil
+}
+
+// spreadstops spaces the colors evenly from low to high
+func spreadstops(colors []string, low, high float64) []ColorStop {
+	stops := make([]ColorStop, len(colors))
+	for i, c := range colors {
+		v := low
+		if len(colors) > 1 {
+			v = low + (high-low)*float64(i)/float64(len(colors)-1)
+		}
+		stops[i] = ColorStop{Value: v, Color: c}
+	}
+	return stops
+}
+
+// Color returns the color of the band, if the value is within it
+func (b ColorBand) Color(v float64) (string, bool) {
+	return b.Fill, v >= b.Min && v <= b.Max
+}
+
+// Stops returns the bounds of the band
+func (b ColorBand) Stops() []float64 {
+	return []float64{b.Min, b.Max}
+}
+
+// Color returns the interpolated color of the value
+func (g GradientScale) Color(v float64) (string, bool) {
+	n := len(g)
+	if n == 0 {
+		return "", false
+	}
+	if v <= g[0].Value {
+		return g[0].Color, true
+	}
+	for i := 1; i < n; i++ {
+		s1, s2 := g[i-1], g[i]
+		if v <= s2.Value {
+			return mixcolor(s1.Color, s2.Color, (v-s1.Value)/(s2.Value-s1.Value)), true
+		}
+	}
+	return g[n-1].Color, true
+}
+
+// Stops returns the values of the stops
+func (g GradientScale) Stops() []float64 {
+	values := make([]float64, len(g))
+	for i, s := range g {
+		values[i] = s.Value
+	}
+	return values
+}
+
+// Color returns the color of the first scale that colors the value
+func (cs ColorScales) Color(v float64) (string, bool) {
+	for _, s := range cs {
+		if color, ok := s.Color(v); ok {
+			return color, true
+		}
+	}
+	return "", false
+}
+
+// Stops returns the stops of all the scales
+func (cs ColorScales) Stops() []float64 {
+	var values []float64
+	for _, s := range cs {
+		values = append(values, s.Stops()...)
+	}
+	sort.Float64s(values)
+	n := 0
+	for i, v := range values {
+		if i == 0 || v != values[n-1] {
+			values[n] = v
+			n++
+		}
+	}
+	return values[:n]
+}
+
+// barstep returns the size of the i-th of n segments of a color bar, relative to its step:
+// all but the last overlap the next, to avoid seams
+func barstep(i, n int) float64 {
+	if i < n-1 {
+		return 1.5
+	}
+	return 1
+}
+
+// mixcolor returns the color at the fraction f of the way from color c1 to c2
+func mixcolor(c1, c2 string, f float64) string {
+	if math.IsNaN(f) || f <= 0 {
+		return c1
+	}
+	if f >= 1 {
+		return c2
+	}
+	a, b := parsecolor(c1, 1), parsecolor(c2, 1)
+	mix := func(x, y uint8) int {
+		return int(math.Round(float64(x) + (float64(y)-float64(x))*f))
+	}
+	return fmt.Sprintf("rgb(%d,%d,%d)", mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B))
+}
+
+// condcolor returns the color of a value in a conditional chart: the condition color,
+// if the value is between cmin and cmax, or without a condition color, the color
+// of the chart's ColorScale, otherwise the fallback color
+func (c *ChartBox) condcolor(v, cmin, cmax float64, color, fallback string) string {
+	if len(color) == 0 && c.ColorScale != nil && v >= cmin && v <= cmax {
+		if sc, ok := c.ColorScale.Color(v); ok {
+			return sc
+		}
+	}
+	return conditionalcolor(v, cmin, cmax, color, fallback)
+}
+
+// ColorBar makes a legend of the chart's ColorScale: a bar of the colors from the first
+// to the last stop, labeled at the stops, placed vertically at the "right" (default)
+// of the chart, or horizontally at the "bottom". Values the scale does not color
+// are shown in the data color.
+func (c *ChartBox) ColorBar(deck Renderer, placement string) {
+	if c.ColorScale == nil {
+		return
+	}
+	stops := c.ColorScale.Stops()
+	if len(stops) < 2 {
+		return
+	}
+	const n = 64
+	textsize := c.TextSize
+	min, max := stops[0], stops[len(stops)-1]
+	color := func(i int) string {
+		v := min + (max-min)*(float64(i)+0.5)/n
+		if sc, ok := c.ColorScale.Color(v); ok {
+			return sc
+		}
+		return c.DataColor
+	}
+	if placement == "bottom" {
+		y := c.Bottom - textsize*5
+		w := (c.Right - c.Left) / n
+		for i := 0; i < n; i++ {
+			sw := w * barstep(i, n)
+			deck.Rect(c.Left+w*float64(i)+sw/2, y, sw, textsize, color(i))
+		}
+		for _, v := range stops {
+			x := MapRange(v, min, max, c.Left, c.Right)
+			deck.TextMid(x, y-textsize*1.75, fmt.Sprintf(c.DataFormat, v), "sans", textsize*0.75, c.LabelColor)
+		}
+		return
+	}
+	x := c.Right + textsize*2
+	h := (c.Top - c.Bottom) / n
+	for i := 0; i < n; i++ {
+		sh := h * barstep(i, n)
+		deck.Rect(x, c.Bottom+h*float64(i)+sh/2, textsize, sh, color(i))
+	}
+	for _, v := range stops {
+		y := MapRange(v, min, max, c.Bottom, c.Top)
+		deck.Text(x+textsize, y-textsize/4, fmt.Sprintf(c.DataFormat, v), "sans", textsize*0.75, c.LabelColor)
+	}
+}
@@ -1,29 +1,47 @@
-// Package dchart2 makes charts using the deck markup
+// Package dchart2 makes charts using the deck markup, or any Renderer
 package dchart2
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/csv"
 	"fmt"
 	"io"
 	"math"
-	"os"
 	"strconv"
 	"strings"
-
-	"github.com/ajstarks/deck/generate"
+	"time"
 )
 
-// NameValue is a name,value pair
+// NameValue is a name,value pair; X is the numeric value of the label, if any.
+// Labels that are dates or times have X values in seconds since the Unix epoch.
+// Samples, if any, are the observations of the label, summarized by the value (see BoxPlot).
 type NameValue struct {
-	Label string
-	Note  string
-	Value float64
+	Label   string
+	Note    string
+	Value   float64
+	X       float64
+	Samples []float64
+}
+
+// Series is a named set of values, one for each label in the chart data
+type Series struct {
+	Name   string
+	Color  string
+	Values []float64
 }
 
-// ChartBox holds the essential data for making a chart
+// ChartBox holds the essential data for making a chart.
+// Data holds the labels, notes and the first set of values;
+// when there is more than one set of values, Series holds all of them.
+// If XValue is set, data is placed at its x value, scaled between XMinvalue and XMaxvalue,
+// otherwise data is evenly spaced in order. XTime places data at its x value as a time.
+// Values are mapped linearly between the base (zero or Minvalue) and Maxvalue,
+// or transformed by YScale, if set. Theme, if set, holds the other colors of the chart,
+// and ColorScale colors the data of conditional charts.
 type ChartBox struct {
 	Data       []NameValue
+	Series     []Series
 	Title      string
 	DataFormat string
 	DataColor  string
@@ -37,24 +55,39 @@ type ChartBox struct {
 	Right      float64
 	Minvalue   float64
 	Maxvalue   float64
+	YScale     Scale
+	XMinvalue  float64
+	XMaxvalue  float64
 	Zerobased  bool
+	XValue     bool
+	XTime      bool
+	Theme      *Theme
+	ColorScale ColorScale
 }
 
 // Flags define chart on/off switches
 type Flags struct {
 	DataMinimum,
+	FreeScale,
 	FullDeck,
 	ReadCSV,
 	ShowAxis,
 	ShowBar,
+	ShowBox,
+	ShowCandle,
 	ShowDonut,
 	ShowVDot,
 	ShowHDot,
 	ShowFrame,
+	ShowDensity,
 	ShowGrid,
 	ShowHBar,
+	ShowHBox,
+	ShowHeatmap,
+	ShowHistogram,
 	ShowLine,
 	ShowNote,
+	ShowOHLC,
 	ShowPercentage,
 	ShowPGrid,
 	ShowPMap,
@@ -62,6 +95,10 @@ type Flags struct {
 	ShowRegressionLine,
 	ShowScatter,
 	ShowSlope,
+	ShowStackedBar,
+	ShowGroupedBar,
+	ShowStackedHBar,
+	ShowPctHBar,
 	ShowSpokes,
 	ShowTitle,
 	ShowValues,
@@ -69,12 +106,17 @@ type Flags struct {
 	ShowWBar,
 	ShowXLast,
 	ShowXstagger,
-	SolidPMap bool
+	SolidPMap,
+	Strict,
+	XTime,
+	XValue bool
 }
 
 // Attributes define chart attributes
 type Attributes struct {
 	BackgroundColor,
+	Bins,
+	ColorBar,
 	DataColor,
 	FrameColor,
 	LabelColor,
@@ -84,13 +126,27 @@ type Attributes struct {
 	CSVCols,
 	DataCondition,
 	DataFmt,
+	DownColor,
+	FacetColumn,
 	HLine,
+	Legend,
+	LegendFlow,
+	LegendSwatch,
 	NoteLocation,
+	Palette,
+	Theme,
+	UpColor,
 	ValuePosition,
-	YAxisR string
+	Whiskers,
+	XAxisR,
+	XTimeFormat,
+	XTimeUnit,
+	YAxisR,
+	YScale string
 }
 
-// Measures define chart measures
+// Measures define chart measures;
+// UserMin and UserMax are the data range, -1 for that of the data
 type Measures struct {
 	TextSize,
 	Left,
@@ -105,6 +161,7 @@ type Measures struct {
 	UserMin,
 	UserMax,
 	VolumeOpacity,
+	VolumePanel,
 	XLabelRotation float64
 	XLabelInterval,
 	PMapLength int
@@ -120,23 +177,62 @@ type Settings struct {
 const (
 	largest      = math.MaxFloat64
 	smallest     = -largest
-	valuecolor   = "rgb(128,0,0)"
-	labelcolor   = "rgb(75,75,75)"
-	dotlinecolor = "lightgray"
 	wbopacity    = 30.0
 	topclock     = math.Pi / 2
 	fullcircle   = math.Pi * 2
 	transparency = 50.0
 )
 
-var blue7 = []string{
-	"rgb(8,69,148)",
-	"rgb(33,113,181)",
-	"rgb(66,146,198)",
-	"rgb(107,174,214)",
-	"rgb(158,202,225)",
-	"rgb(198,219,239)",
-	"rgb(239,243,255)",
+// timelayouts are the layouts used to parse dates and times
+var timelayouts = []string{
+	"2006-01-02",
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006/01/02",
+	"01/02/2006",
+	"2006-01",
+	"02-Jan-2006",
+	"2 Jan 2006",
+	"Jan 2, 2006",
+	"January 2, 2006",
+	"Jan 2006",
+	"January 2006",
+	time.RFC1123,
+	time.RFC1123Z,
+}
+
+// timesteps are the calendar units used for time axis labels, in increasing order
+var timesteps = []struct {
+	unit    string
+	n       int
+	seconds float64
+}{
+	{"day", 1, 86400},
+	{"day", 2, 2 * 86400},
+	{"week", 1, 7 * 86400},
+	{"week", 2, 14 * 86400},
+	{"month", 1, 30.44 * 86400},
+	{"quarter", 1, 91.31 * 86400},
+	{"month", 6, 182.62 * 86400},
+	{"year", 1, 365.25 * 86400},
+	{"year", 2, 2 * 365.25 * 86400},
+	{"year", 5, 5 * 365.25 * 86400},
+	{"year", 10, 10 * 365.25 * 86400},
+	{"year", 25, 25 * 365.25 * 86400},
+	{"year", 50, 50 * 365.25 * 86400},
+	{"year", 100, 100 * 365.25 * 86400},
+}
+
+// timeformats are the default label formats for time units
+var timeformats = map[string]string{
+	"day":     "Jan 2",
+	"week":    "Jan 2",
+	"month":   "Jan 2006",
+	"quarter": "2006",
+	"year":    "2006",
 }
 
 var xmlmap = strings.NewReplacer(
@@ -144,59 +240,256 @@ var xmlmap = strings.NewReplacer(
 	"<", "&lt;",
 	">", "&gt;")
 
+var xmlunmap = strings.NewReplacer(
+	"&amp;", "&",
+	"&lt;", "<",
+	"&gt;", ">")
+
 // xmlesc escapes XML
 func xmlesc(s string) string {
 	return xmlmap.Replace(s)
 }
 
+// csvparseerror converts an error reading CSV to a ParseError, with the line of the error
+func csvparseerror(err error) error {
+	if cerr, ok := err.(*csv.ParseError); ok {
+		return &ParseError{Line: cerr.Line, Err: cerr.Err}
+	}
+	return err
+}
+
+// xmlunesc reverses xmlesc, for renderers that do not use XML
+func xmlunesc(s string) string {
+	return xmlunmap.Replace(s)
+}
+
 // getheader returns the indicies of the comma-separated list of fields
-// by default or on error, return 0, 1. For example given this header:
+// the first field names the labels, the rest name the values.
+// by default or on error, return 0, [1]. For example given this header:
 //
 // first,second,third,sum
 //
-// first,sum returns 0,3 and first,third returns 0,2
-func getheader(s []string, lv string) (int, int) {
+// first,sum returns 0,[3] and first,second,third returns 0,[1,2]
+func getheader(s []string, lv string) (int, []int) {
 	li := 0
-	vi := 1
+	vi := []int{}
 	cv := strings.Split(lv, ",")
-	if len(cv) != 2 {
-		return li, vi
+	if len(cv) < 2 {
+		return li, []int{1}
 	}
 	for i, p := range s {
 		if p == cv[0] {
 			li = i
 		}
-		if p == cv[1] {
-			vi = i
+	}
+	for _, name := range cv[1:] {
+		for i, p := range s {
+			if p == name {
+				vi = append(vi, i)
+				break
+			}
 		}
 	}
+	if len(vi) == 0 {
+		vi = append(vi, 1)
+	}
 	return li, vi
 }
 
-// zerobase uses the correct base for scaling
+// rowvalues splits the fields following the label into values and an optional note.
+// The last field is the note, unless it is a number. Values that are not numbers
+// are zero, or if strict, errors.
+func rowvalues(fields []string, strict bool) ([]float64, string, error) {
+	note := ""
+	n := len(fields)
+	if n > 1 {
+		if _, err := strconv.ParseFloat(fields[n-1], 64); err != nil {
+			note = fields[n-1]
+			n--
+		}
+	}
+	values := make([]float64, n)
+	for i := 0; i < n; i++ {
+		v, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			if strict {
+				return nil, note, err
+			}
+			v = 0
+		}
+		values[i] = v
+	}
+	return values, note, nil
+}
+
+// makeseries makes the series from rows of values, using the names if present.
+// A single column of values makes no series, since Data holds the values.
+func makeseries(rows [][]float64, names []string) []Series {
+	ns := 0
+	for _, r := range rows {
+		if len(r) > ns {
+			ns = len(r)
+		}
+	}
+	if ns < 2 {
+		return nil
+	}
+	series := make([]Series, ns)
+	for k := range series {
+		if k < len(names) {
+			series[k].Name = xmlesc(names[k])
+		}
+		series[k].Values = make([]float64, len(rows))
+		for i, r := range rows {
+			if k < len(r) {
+				series[k].Values[i] = r[k]
+			}
+		}
+	}
+	return series
+}
+
+// minmax returns the minimum and maximum of rows of values
+func minmax(rows [][]float64) (float64, float64) {
+	minval := largest
+	maxval := smallest
+	for _, r := range rows {
+		for _, v := range r {
+			if v > maxval {
+				maxval = v
+			}
+			if v < minval {
+				minval = v
+			}
+		}
+	}
+	return minval, maxval
+}
+
+// labelx returns the numeric value of a label, or its time in seconds since the Unix epoch,
+// or the default if the label is neither a number nor a time
+func labelx(label string, def float64) float64 {
+	label = strings.TrimSpace(label)
+	x, err := strconv.ParseFloat(label, 64)
+	if err == nil {
+		return x
+	}
+	t, err := parsetime(label)
+	if err == nil {
+		return float64(t.Unix())
+	}
+	return def
+}
+
+// timex returns the time of a label in seconds since the Unix epoch, for time axes:
+// labels that are not dates, times or years are read by labelx
+func timex(label string, def float64) float64 {
+	label = strings.TrimSpace(label)
+	if t, err := labeltime(label); err == nil {
+		return float64(t.Unix())
+	}
+	return labelx(label, def)
+}
+
+// labeltime parses a label as a four-digit year, or a date or time (see parsetime)
+func labeltime(label string) (time.Time, error) {
+	if len(label) == 4 && strings.Trim(label, "0123456789") == "" {
+		return time.Parse("2006", label)
+	}
+	return parsetime(label)
+}
+
+// datetimes reports whether every label of the data is a date, time or year (see labeltime)
+func (c *ChartBox) datetimes() bool {
+	for _, d := range c.Data {
+		if _, err := labeltime(strings.TrimSpace(xmlunesc(d.Label))); err != nil {
+			return false
+		}
+	}
+	return len(c.Data) > 0
+}
+
+// settimes sets the x values of the data to the times of the labels (see timex)
+func (c *ChartBox) settimes() {
+	for i := range c.Data {
+		c.Data[i].X = timex(xmlunesc(c.Data[i].Label), float64(i))
+	}
+	c.XMinvalue, c.XMaxvalue = xminmax(c.Data)
+}
+
+// parsetime parses a date or time using the common layouts
+func parsetime(s string) (time.Time, error) {
+	for _, layout := range timelayouts {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%s is not a date or time", s)
+}
+
+// xminmax returns the minimum and maximum of the x values of the data
+func xminmax(data []NameValue) (float64, float64) {
+	minval := largest
+	maxval := smallest
+	for _, d := range data {
+		if d.X > maxval {
+			maxval = d.X
+		}
+		if d.X < minval {
+			minval = d.X
+		}
+	}
+	return minval, maxval
+}
+
+// zerobase uses the correct base for scaling:
+// zero, or the minimum if it is negative, when zero based
 func zerobase(usez bool, n float64) float64 {
 	if usez {
-		return 0
+		return math.Min(0, n)
 	}
 	return n
 }
 
 // ReadTSV reads tab separated values into a ChartBox
 // default values for the top, bottom, left, right (90,50,10,90) are filled in
-// as is the default color, black
+// as is the default color, black.
+// Each line has a label, one or more values, and an optional note;
+// numeric labels are also read as x values (see XValue).
+// A comment line of tab separated names (#<tab>name1<tab>name2...)
+// names the series when there is more than one value per line.
+// Values that are not numbers are read as zero.
 func ReadTSV(r io.Reader) (ChartBox, error) {
+	return readtsv(r, false)
+}
+
+// ReadTSVStrict reads tab separated values like ReadTSV,
+// but values that are not numbers are errors (*ParseError)
+func ReadTSVStrict(r io.Reader) (ChartBox, error) {
+	return readtsv(r, true)
+}
+
+// readtsv reads tab separated values, strictly or not
+func readtsv(r io.Reader, strict bool) (ChartBox, error) {
 	var d NameValue
 	var data []NameValue
+	var rows [][]float64
+	var names []string
 	var err error
-	maxval := smallest
-	minval := largest
 	title := ""
+	line := 0
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
+		line++
 		t := scanner.Text()
 		if len(t) == 0 { // skip blank lines
 			continue
 		}
+		if strings.HasPrefix(t, "#\t") { // process series names
+			names = strings.Split(t[2:], "\t")
+			continue
+		}
 		if t[0] == '#' && len(t) > 2 { // process titles
 			title = strings.TrimSpace(t[1:])
 			continue
@@ -205,35 +498,33 @@ func ReadTSV(r io.Reader) (ChartBox, error) {
 		if len(fields) < 2 {
 			continue
 		}
-		if len(fields) == 3 {
-			d.Note = fields[2]
-		} else {
-			d.Note = ""
+		values, note, verr := rowvalues(fields[1:], strict)
+		if verr != nil {
+			return ChartBox{}, &ParseError{Line: line, Err: verr}
 		}
 		d.Label = fields[0]
-		d.Value, err = strconv.ParseFloat(fields[1], 64)
-		if err != nil {
-			d.Value = 0
-		}
-		if d.Value > maxval {
-			maxval = d.Value
-		}
-		if d.Value < minval {
-			minval = d.Value
-		}
+		d.Note = note
+		d.Value = values[0]
+		d.X = labelx(d.Label, float64(len(data)))
 		data = append(data, d)
+		rows = append(rows, values)
 	}
 	err = scanner.Err()
+	minval, maxval := minmax(rows)
+	xminval, xmaxval := xminmax(data)
 	return ChartBox{
 		Title:      xmlesc(title),
 		Data:       data,
+		Series:     makeseries(rows, names),
 		Minvalue:   minval,
 		Maxvalue:   maxval,
+		XMinvalue:  xminval,
+		XMaxvalue:  xmaxval,
 		TextSize:   1.2,
 		DataFormat: "%.1f",
 		DataColor:  "rgb(128,128,128)",
-		LabelColor: labelcolor,
-		ValueColor: valuecolor,
+		LabelColor: lighttheme.Label,
+		ValueColor: lighttheme.Value,
 		Opacity:    100,
 		Left:       10,
 		Right:      90,
@@ -245,20 +536,37 @@ func ReadTSV(r io.Reader) (ChartBox, error) {
 
 // ReadCSV reads CSV values into a ChartBox
 // default values for the top, bottom, left, right (90,50,10,90) are filled in
-// as is the default color, black
+// as is the default color, black.
+// csvcols names the label column followed by one or more value columns,
+// for example "Date,Open,Close"; each value column is a series.
+// Without csvcols, the first column is the label, the second the value,
+// and a third column, if any, the note.
+// Numeric labels are also read as x values (see XValue).
+// Values that are not numbers are read as zero, and malformed rows are skipped.
 func ReadCSV(r io.Reader, csvcols string) (ChartBox, error) {
+	return readcsv(r, csvcols, false)
+}
+
+// ReadCSVStrict reads CSV values like ReadCSV, but values that are not numbers,
+// and malformed rows, are errors (*ParseError)
+func ReadCSVStrict(r io.Reader, csvcols string) (ChartBox, error) {
+	return readcsv(r, csvcols, true)
+}
+
+// readcsv reads CSV values, strictly or not
+func readcsv(r io.Reader, csvcols string, strict bool) (ChartBox, error) {
 	var (
-		data []NameValue
-		d    NameValue
-		err  error
+		data   []NameValue
+		rows   [][]float64
+		names  []string
+		d      NameValue
+		values []float64
 	)
 	input := csv.NewReader(r)
-	maxval := smallest
-	minval := largest
 	title := ""
 	n := 0
 	li := 0
-	vi := 1
+	vi := []int{1}
 	for {
 		n++
 		fields, csverr := input.Read()
@@ -266,7 +574,9 @@ func ReadCSV(r io.Reader, csvcols string) (ChartBox, error) {
 			break
 		}
 		if csverr != nil {
-			fmt.Fprintf(os.Stderr, "%v %v\n", csverr, fields)
+			if strict {
+				return ChartBox{}, csvparseerror(csverr)
+			}
 			continue
 		}
 
@@ -277,76 +587,260 @@ func ReadCSV(r io.Reader, csvcols string) (ChartBox, error) {
 			title = fields[1]
 			continue
 		}
-		if len(fields) == 3 {
-			d.Note = xmlesc(fields[2])
-		} else {
-			d.Note = ""
-		}
 		if n == 1 && len(csvcols) > 0 { // column header is assumed to be the first row
 			li, vi = getheader(fields, csvcols)
-			title = fields[vi]
+			names = make([]string, len(vi))
+			for i, c := range vi {
+				names[i] = fields[c]
+			}
+			title = strings.Join(names, ", ")
 			continue
 		}
 
 		d.Label = xmlesc(fields[li])
-		d.Value, err = strconv.ParseFloat(fields[vi], 64)
-		if err != nil {
-			d.Value = 0
-		}
-		if d.Value > maxval {
-			maxval = d.Value
-		}
-		if d.Value < minval {
-			minval = d.Value
+		d.X = labelx(fields[li], float64(len(data)))
+		if len(csvcols) > 0 {
+			d.Note = ""
+			values = make([]float64, len(vi))
+			for i, c := range vi {
+				if c >= len(fields) {
+					continue
+				}
+				v, verr := strconv.ParseFloat(fields[c], 64)
+				if verr != nil && strict {
+					line, _ := input.FieldPos(c)
+					return ChartBox{}, &ParseError{Line: line, Err: verr}
+				}
+				values[i] = v
+			}
+		} else {
+			v, verr := strconv.ParseFloat(fields[1], 64)
+			if verr != nil && strict {
+				line, _ := input.FieldPos(1)
+				return ChartBox{}, &ParseError{Line: line, Err: verr}
+			}
+			values = []float64{v}
+			d.Note = ""
+			if len(fields) == 3 {
+				d.Note = xmlesc(fields[2])
+			}
 		}
+		d.Value = values[0]
 		data = append(data, d)
+		rows = append(rows, values)
 	}
+	minval, maxval := minmax(rows)
+	xminval, xmaxval := xminmax(data)
 	return ChartBox{
 		Title:      xmlesc(title),
 		Data:       data,
+		Series:     makeseries(rows, names),
 		Minvalue:   minval,
 		Maxvalue:   maxval,
+		XMinvalue:  xminval,
+		XMaxvalue:  xmaxval,
 		TextSize:   1.2,
 		DataFormat: "%.1f",
 		DataColor:  "rgb(128,128,128)",
-		LabelColor: labelcolor,
-		ValueColor: valuecolor,
+		LabelColor: lighttheme.Label,
+		ValueColor: lighttheme.Value,
 		Opacity:    100,
 		Left:       10,
 		Right:      90,
 		Top:        90,
 		Bottom:     50,
 		Zerobased:  true,
-	}, err
+	}, nil
+}
+
+// ReadCSVGroups reads long-format CSV values into a ChartBox for each group:
+// the rows with the same value in the group column, in order of appearance.
+// The first row is the column header. csvcols selects the label and value columns
+// of each chart, as in ReadCSV; by default, the first column other than the group column
+// is the label, and the others are values. Each chart is titled with its group.
+// Values are read as in ReadCSV.
+func ReadCSVGroups(r io.Reader, groupcol, csvcols string) ([]ChartBox, error) {
+	return readcsvgroups(r, groupcol, csvcols, false)
+}
+
+// readcsvgroups reads long-format CSV values, strictly or not
+func readcsvgroups(r io.Reader, groupcol, csvcols string, strict bool) ([]ChartBox, error) {
+	var records [][]string
+	var lines []int // the line of each record
+	input := csv.NewReader(r)
+	for {
+		rec, err := input.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if strict || len(records) == 0 {
+				return nil, csvparseerror(err)
+			}
+			continue
+		}
+		line, _ := input.FieldPos(0)
+		records = append(records, rec)
+		lines = append(lines, line)
+	}
+	if len(records) < 2 {
+		return nil, &DataError{Chart: "small multiples", Need: 1, Have: 0}
+	}
+	header := records[0]
+	gi := -1
+	var cols []string
+	for i, name := range header {
+		if name == groupcol {
+			gi = i
+		} else {
+			cols = append(cols, name)
+		}
+	}
+	if gi < 0 {
+		return nil, &ArgumentError{Name: groupcol, Reason: "no such column"}
+	}
+	if len(csvcols) == 0 {
+		csvcols = strings.Join(cols, ",")
+	}
+	var order []string
+	groups := map[string][][]string{}
+	grouplines := map[string][]int{}
+	for i, rec := range records[1:] {
+		if gi >= len(rec) {
+			continue
+		}
+		g := rec[gi]
+		if _, ok := groups[g]; !ok {
+			order = append(order, g)
+		}
+		groups[g] = append(groups[g], rec)
+		grouplines[g] = append(grouplines[g], lines[i+1])
+	}
+	charts := make([]ChartBox, len(order))
+	for i, g := range order {
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write(header)
+		w.WriteAll(groups[g])
+		var err error
+		charts[i], err = readcsv(&buf, csvcols, strict)
+		if perr, ok := err.(*ParseError); ok && perr.Line >= 2 && perr.Line-2 < len(grouplines[g]) {
+			perr.Line = grouplines[g][perr.Line-2] // the line of the group's record in the input
+		}
+		if err != nil {
+			return nil, err
+		}
+		charts[i].Title = xmlesc(g)
+	}
+	return charts, nil
 }
 
 // chart types
 
-// Bar makes a (column) bar chart
-func (c *ChartBox) Bar(deck *generate.Deck, size float64) {
-	dlen := float64(len(c.Data) - 1)
-	ymin := zerobase(c.Zerobased, c.Minvalue)
-	for i, d := range c.Data {
-		x := MapRange(float64(i), 0, dlen, c.Left, c.Right)
-		y := MapRange(d.Value, ymin, c.Maxvalue, c.Bottom, c.Top)
-		deck.Line(x, c.Bottom, x, y, size, c.DataColor, c.Opacity)
+// Bar makes a (column) bar chart; the bars of several series share
+// the label slot of the specified size, side by side
+func (c *ChartBox) Bar(deck Renderer, size float64) error {
+	if err := c.need("bar", 1); err != nil {
+		return err
+	}
+	series := c.series()
+	for k, s := range series {
+		offset, bw := barslot(k, len(series), size)
+		for i, v := range s.Values {
+			x := c.xpos(i) + offset
+			y := c.ypos(v)
+			c.item(deck, k, s, i)
+			deck.Line(x, c.ybase(), x, y, bw, s.Color, c.Opacity)
+			enditem(deck)
+		}
 	}
+	return nil
 }
 
-// ConditionalBar makes a bar chart with conditional coloring
-func (c *ChartBox) ConditionalBar(deck *generate.Deck, size float64, cmin, cmax float64, color string) {
-	dlen := float64(len(c.Data) - 1)
-	ymin := zerobase(c.Zerobased, c.Minvalue)
-	for i, d := range c.Data {
-		v := d.Value
-		x := MapRange(float64(i), 0, dlen, c.Left, c.Right)
-		y := MapRange(v, ymin, c.Maxvalue, c.Bottom, c.Top)
-		deck.Line(x, c.Bottom, x, y, size, conditionalcolor(v, cmin, cmax, color, c.DataColor), c.Opacity)
+// ConditionalBar makes a bar chart with conditional coloring, with the bars
+// of several series side by side, like Bar
+func (c *ChartBox) ConditionalBar(deck Renderer, size float64, cmin, cmax float64, color string) error {
+	if err := c.need("bar", 1); err != nil {
+		return err
+	}
+	if err := condition(cmin, cmax, color); err != nil {
+		return err
+	}
+	series := c.series()
+	for k, s := range series {
+		offset, bw := barslot(k, len(series), size)
+		for i, v := range s.Values {
+			x := c.xpos(i) + offset
+			y := c.ypos(v)
+			c.item(deck, k, s, i)
+			deck.Line(x, c.ybase(), x, y, bw, c.condcolor(v, cmin, cmax, color, s.Color), c.Opacity)
+			enditem(deck)
+		}
+	}
+	return nil
+}
+
+// StackedBar makes a stacked (column) bar chart, with the segments for each series
+// adding up at each label. The sums are scaled to Maxvalue (see StackMax).
+// Negative values stack down from the zero baseline.
+func (c *ChartBox) StackedBar(deck Renderer, size float64) error {
+	if err := c.need("stacked bar", 1); err != nil {
+		return err
+	}
+	series := c.series()
+	for i := range c.Data {
+		x := c.xpos(i)
+		pos, neg := 0.0, 0.0
+		for k, s := range series {
+			v := s.Values[i]
+			var y1, y2 float64
+			if v < 0 {
+				y1 = c.ypos(neg)
+				neg += v
+				y2 = c.ypos(neg)
+			} else {
+				y1 = c.ypos(pos)
+				pos += v
+				y2 = c.ypos(pos)
+			}
+			c.item(deck, k, s, i)
+			deck.Line(x, y1, x, y2, size, s.Color, c.Opacity)
+			enditem(deck)
+		}
 	}
+	return nil
+}
+
+// GroupedBar makes a grouped (column) bar chart, with the bars for each series
+// side by side within the label slot of the specified size, as Bar does
+func (c *ChartBox) GroupedBar(deck Renderer, size float64) error {
+	return c.Bar(deck, size)
+}
+
+// barslot returns the offset from the label and the width of the bar
+// of the k-th of n series sharing a label slot of the specified size
+func barslot(k, n int, size float64) (float64, float64) {
+	bw := size / float64(n)
+	return (float64(k) - float64(n-1)/2) * bw, bw
+}
+
+// StackMax returns the largest sum of the series values at any label,
+// suitable for the Maxvalue of a stacked chart
+func (c *ChartBox) StackMax() float64 {
+	max := smallest
+	for _, sum := range stacksums(c.series(), len(c.Data)) {
+		if sum > max {
+			max = sum
+		}
+	}
+	return max
 }
 
 // WBar makes a word-based horizontal bar chart
-func (c *ChartBox) WBar(deck *generate.Deck, linespacing float64, showval, showpct bool) {
+func (c *ChartBox) WBar(deck Renderer, linespacing float64, showval, showpct bool) error {
+	if err := c.need("word bar", 1); err != nil {
+		return err
+	}
 	textsize := c.TextSize
 	format := c.DataFormat
 	data := c.Data
@@ -360,11 +854,12 @@ func (c *ChartBox) WBar(deck *generate.Deck, linespacing float64, showval, showp
 	right := c.Right
 	hts := textsize / 2
 	mts := textsize
-	ymin := zerobase(c.Zerobased, c.Minvalue)
 	for _, d := range data {
 		deck.Text(left+hts, y, d.Label, "sans", textsize, c.LabelColor)
-		bv := MapRange(d.Value, ymin, c.Maxvalue, left, right)
-		deck.Line(left+hts, y+hts, bv, y+hts, textsize*1.5, c.DataColor, wbopacity)
+		bv := c.vmap(d.Value, left, right)
+		startitem(deck, -1, "", d.Label, fmt.Sprintf(format, d.Value), d.Note)
+		deck.Line(math.Max(c.xbase(), left+hts), y+hts, bv, y+hts, textsize*1.5, c.DataColor, wbopacity)
+		enditem(deck)
 		if showval {
 			if showpct {
 				avgs := fmt.Sprintf(" ("+format+"%%)", 100*(d.Value/sum))
@@ -375,142 +870,260 @@ func (c *ChartBox) WBar(deck *generate.Deck, linespacing float64, showval, showp
 		}
 		y -= linespacing
 	}
+	return nil
 }
 
 // HBar makes a horizontal bar chart
-func (c *ChartBox) HBar(deck *generate.Deck, size, linespacing float64) {
+func (c *ChartBox) HBar(deck Renderer, size, linespacing float64) error {
+	if err := c.need("horizontal bar", 1); err != nil {
+		return err
+	}
 	y := c.Top
 	textsize := c.TextSize
 	format := c.DataFormat
 
-	xmin := zerobase(c.Zerobased, c.Minvalue)
 	for _, d := range c.Data {
 		v := d.Value
 		deck.TextEnd(c.Left-textsize, y-size/2, d.Label, "sans", textsize, c.LabelColor)
-		x2 := MapRange(v, xmin, c.Maxvalue, c.Left, c.Right)
-		deck.Line(c.Left, y, x2, y, size, c.DataColor, c.Opacity)
-		deck.Text(x2+(textsize/2), y-size/2, fmt.Sprintf(format, v), "mono", textsize*0.75, c.ValueColor)
+		x2 := c.vmap(v, c.Left, c.Right)
+		startitem(deck, -1, "", d.Label, fmt.Sprintf(format, v), d.Note)
+		deck.Line(c.xbase(), y, x2, y, size, c.DataColor, c.Opacity)
+		enditem(deck)
+		if v < 0 {
+			deck.TextEnd(x2-(textsize/2), y-size/2, fmt.Sprintf(format, v), "mono", textsize*0.75, c.ValueColor)
+		} else {
+			deck.Text(x2+(textsize/2), y-size/2, fmt.Sprintf(format, v), "mono", textsize*0.75, c.ValueColor)
+		}
 		y -= linespacing
 	}
+	return nil
 }
 
 // ConditionalHBar makes a horizontal bar chart with conditional coloring
-func (c *ChartBox) ConditionalHBar(deck *generate.Deck, size, linespacing float64, cmin, cmax float64, color string) {
-
+func (c *ChartBox) ConditionalHBar(deck Renderer, size, linespacing float64, cmin, cmax float64, color string) error {
+	if err := c.need("horizontal bar", 1); err != nil {
+		return err
+	}
+	if err := condition(cmin, cmax, color); err != nil {
+		return err
+	}
 	y := c.Top
-	xmin := zerobase(c.Zerobased, c.Minvalue)
 	for _, d := range c.Data {
 		v := d.Value
 		deck.TextEnd(c.Left-2, y-size/2, d.Label, "sans", c.TextSize, c.LabelColor)
-		x2 := MapRange(v, xmin, c.Maxvalue, c.Left, c.Right)
-		deck.Line(c.Left, y, x2, y, size, conditionalcolor(v, cmin, cmax, color, c.DataColor), c.Opacity)
+		x2 := c.vmap(v, c.Left, c.Right)
+		startitem(deck, -1, "", d.Label, fmt.Sprintf(c.DataFormat, v), d.Note)
+		deck.Line(c.xbase(), y, x2, y, size, c.condcolor(v, cmin, cmax, color, c.DataColor), c.Opacity)
+		enditem(deck)
+		y -= linespacing
+	}
+	return nil
+}
+
+// StackedHBar makes a stacked horizontal bar chart, with a segment for each series
+// and a legend of the segments above the bars. If normalized, each bar is scaled
+// to 100%, otherwise the sums are scaled to Maxvalue (see StackMax).
+// If showpct, the percentage of the row total is shown inside each segment.
+func (c *ChartBox) StackedHBar(deck Renderer, size, linespacing float64, normalized, showpct bool) error {
+	if err := c.need("stacked horizontal bar", 1); err != nil {
+		return err
+	}
+	textsize := c.TextSize
+	format := c.DataFormat
+	series := c.series()
+	xmin := zerobase(c.Zerobased, c.Minvalue)
+	xmax := c.Maxvalue
+	if normalized {
+		xmin, xmax = 0, 100
+	}
+	y := c.Top
+	c.segmentlegend(deck, y+linespacing, series)
+	row := make([]NameValue, len(series))
+	for i, d := range c.Data {
+		for k, s := range series {
+			row[k].Value = s.Values[i]
+		}
+		rowpct := pct(row)
+		deck.TextEnd(c.Left-textsize, y-size/2, d.Label, "sans", textsize, c.LabelColor)
+		x1 := c.Left
+		sum := 0.0
+		for k, s := range series {
+			if normalized {
+				sum += rowpct[k]
+			} else {
+				sum += s.Values[i]
+			}
+			x2 := MapRange(sum, xmin, xmax, c.Left, c.Right)
+			color, op := c.stdcolor(k, s.Color, c.Opacity, true)
+			startitem(deck, k, s.Name, d.Label, fmt.Sprintf(format+" ("+format+"%%)", s.Values[i], rowpct[k]), d.Note)
+			deck.Line(x1, y, x2, y, size, color, op)
+			enditem(deck)
+			if showpct && x2-x1 > textsize*3 {
+				deck.TextMid(x1+(x2-x1)/2, y-(textsize/3), fmt.Sprintf(format+"%%", rowpct[k]), "sans", textsize*0.75, c.theme().Contrast)
+			}
+			x1 = x2
+		}
 		y -= linespacing
 	}
+	return nil
 }
 
 // Line makes a line chart
-func (c *ChartBox) Line(deck *generate.Deck, size float64) {
+func (c *ChartBox) Line(deck Renderer, size float64) error {
+	if err := c.need("line", 2); err != nil {
+		return err
+	}
 	n := len(c.Data)
-	fn := float64(n - 1)
-	ymin := zerobase(c.Zerobased, c.Minvalue)
-	for i := 0; i < n-1; i++ {
-		v1 := c.Data[i].Value
-		v2 := c.Data[i+1].Value
-		x1 := MapRange(float64(i), 0, fn, c.Left, c.Right)
-		y1 := MapRange(v1, ymin, c.Maxvalue, c.Bottom, c.Top)
-		x2 := MapRange(float64(i+1), 0, fn, c.Left, c.Right)
-		y2 := MapRange(v2, ymin, c.Maxvalue, c.Bottom, c.Top)
-		deck.Line(x1, y1, x2, y2, size, c.DataColor, c.Opacity)
+	for k, s := range c.series() {
+		for i := 0; i < n-1; i++ {
+			v1 := s.Values[i]
+			v2 := s.Values[i+1]
+			x1 := c.xpos(i)
+			y1 := c.ypos(v1)
+			x2 := c.xpos(i + 1)
+			y2 := c.ypos(v2)
+			c.item(deck, k, s, i+1)
+			deck.Line(x1, y1, x2, y2, size, s.Color, c.Opacity)
+			enditem(deck)
+		}
 	}
+	return nil
 }
 
 // ConditionalLine makes a line chart with conditional coloring
-func (c *ChartBox) ConditionalLine(deck *generate.Deck, size float64, cmin, cmax float64, color string) {
+func (c *ChartBox) ConditionalLine(deck Renderer, size float64, cmin, cmax float64, color string) error {
+	if err := c.need("line", 2); err != nil {
+		return err
+	}
+	if err := condition(cmin, cmax, color); err != nil {
+		return err
+	}
 	n := len(c.Data)
-	fn := float64(n - 1)
-	ymin := zerobase(c.Zerobased, c.Minvalue)
-	for i := 0; i < n-1; i++ {
-		v1 := c.Data[i].Value
-		v2 := c.Data[i+1].Value
-		x1 := MapRange(float64(i), 0, fn, c.Left, c.Right)
-		y1 := MapRange(v1, ymin, c.Maxvalue, c.Bottom, c.Top)
-		x2 := MapRange(float64(i+1), 0, fn, c.Left, c.Right)
-		y2 := MapRange(v2, ymin, c.Maxvalue, c.Bottom, c.Top)
-		deck.Line(x1, y1, x2, y2, size, conditionalcolor(v1, cmin, cmax, color, c.DataColor), c.Opacity)
+	for k, s := range c.series() {
+		for i := 0; i < n-1; i++ {
+			v1 := s.Values[i]
+			v2 := s.Values[i+1]
+			x1 := c.xpos(i)
+			y1 := c.ypos(v1)
+			x2 := c.xpos(i + 1)
+			y2 := c.ypos(v2)
+			c.item(deck, k, s, i+1)
+			deck.Line(x1, y1, x2, y2, size, c.condcolor(v1, cmin, cmax, color, s.Color), c.Opacity)
+			enditem(deck)
+		}
 	}
+	return nil
 }
 
 // Scatter makes a scatter chart
-func (c *ChartBox) Scatter(deck *generate.Deck, size float64) {
-	dlen := float64(len(c.Data) - 1)
-	ymin := zerobase(c.Zerobased, c.Minvalue)
-	for i, d := range c.Data {
-		x := MapRange(float64(i), 0, dlen, c.Left, c.Right)
-		y := MapRange(d.Value, ymin, c.Maxvalue, c.Bottom, c.Top)
-		deck.Circle(x, y, size, c.DataColor, c.Opacity)
+func (c *ChartBox) Scatter(deck Renderer, size float64) error {
+	if err := c.need("scatter", 1); err != nil {
+		return err
+	}
+	for k, s := range c.series() {
+		for i, v := range s.Values {
+			x := c.xpos(i)
+			y := c.ypos(v)
+			c.item(deck, k, s, i)
+			deck.Circle(x, y, size, s.Color, c.Opacity)
+			enditem(deck)
+		}
 	}
+	return nil
 }
 
 // ConditionalScatter makes a scatter chart
-func (c *ChartBox) ConditionalScatter(deck *generate.Deck, size float64, cmin, cmax float64, color string) {
-	dlen := float64(len(c.Data) - 1)
-	ymin := zerobase(c.Zerobased, c.Minvalue)
-	for i, d := range c.Data {
-		v := d.Value
-		x := MapRange(float64(i), 0, dlen, c.Left, c.Right)
-		y := MapRange(v, ymin, c.Maxvalue, c.Bottom, c.Top)
-		deck.Circle(x, y, size, conditionalcolor(v, cmin, cmax, color, c.DataColor), c.Opacity)
+func (c *ChartBox) ConditionalScatter(deck Renderer, size float64, cmin, cmax float64, color string) error {
+	if err := c.need("scatter", 1); err != nil {
+		return err
+	}
+	if err := condition(cmin, cmax, color); err != nil {
+		return err
+	}
+	for k, s := range c.series() {
+		for i, v := range s.Values {
+			x := c.xpos(i)
+			y := c.ypos(v)
+			c.item(deck, k, s, i)
+			deck.Circle(x, y, size, c.condcolor(v, cmin, cmax, color, s.Color), c.Opacity)
+			enditem(deck)
+		}
 	}
+	return nil
 }
 
 // Area makes a area chart
-func (c *ChartBox) Area(deck *generate.Deck) {
+func (c *ChartBox) Area(deck Renderer) error {
+	if err := c.need("area", 2); err != nil {
+		return err
+	}
 	n := len(c.Data)
-	fn := float64(n - 1)
-	ymin := zerobase(c.Zerobased, c.Minvalue)
-	xvol := make([]float64, n+2)
-	yvol := make([]float64, n+2)
-	xvol[0] = c.Left
-	yvol[0] = c.Bottom
-	xvol[n+1] = c.Right
-	yvol[n+1] = c.Bottom
-
-	for i := 0; i < n; i++ {
-		xvol[i+1] = MapRange(float64(i), 0, fn, c.Left, c.Right)
-		yvol[i+1] = MapRange(c.Data[i].Value, ymin, c.Maxvalue, c.Bottom, c.Top)
+	for k, s := range c.series() {
+		xvol := make([]float64, n+2)
+		yvol := make([]float64, n+2)
+		xvol[0] = c.xpos(0)
+		yvol[0] = c.ybase()
+		xvol[n+1] = c.xpos(n - 1)
+		yvol[n+1] = c.ybase()
+
+		for i := 0; i < n; i++ {
+			xvol[i+1] = c.xpos(i)
+			yvol[i+1] = c.ypos(s.Values[i])
+		}
+		startitem(deck, k, s.Name, s.Name, "", "")
+		deck.Polygon(xvol, yvol, s.Color, c.Opacity)
+		enditem(deck)
 	}
-	deck.Polygon(xvol, yvol, c.DataColor, c.Opacity)
+	return nil
 }
 
 // HDot makes a dotted horizontal bar chart
-func (c *ChartBox) HDot(deck *generate.Deck, size, linespacing float64) {
+func (c *ChartBox) HDot(deck Renderer, size, linespacing float64) error {
+	if err := c.need("dot", 1); err != nil {
+		return err
+	}
 	textsize := c.TextSize
 	format := c.DataFormat
 	y := c.Top
-	xmin := zerobase(c.Zerobased, c.Minvalue)
 	for _, d := range c.Data {
 		deck.TextEnd(c.Left-textsize, y-size/2, d.Label, "sans", textsize, c.LabelColor)
-		x2 := MapRange(d.Value, xmin, c.Maxvalue, c.Left, c.Right)
-		deck.Text(x2+textsize/2, y-size/2, fmt.Sprintf(format, d.Value), "mono", textsize*0.75, c.ValueColor)
-		dottedhline(deck, c.Left, y, x2, size, size*2, c.DataColor)
+		x1 := c.xbase()
+		x2 := c.vmap(d.Value, c.Left, c.Right)
+		startitem(deck, -1, "", d.Label, fmt.Sprintf(format, d.Value), d.Note)
+		if d.Value < 0 {
+			deck.TextEnd(x2-textsize/2, y-size/2, fmt.Sprintf(format, d.Value), "mono", textsize*0.75, c.ValueColor)
+			dottedhline(deck, x2, y, x1, size, size*2, c.DataColor)
+		} else {
+			deck.Text(x2+textsize/2, y-size/2, fmt.Sprintf(format, d.Value), "mono", textsize*0.75, c.ValueColor)
+			dottedhline(deck, x1, y, x2, size, size*2, c.DataColor)
+		}
+		enditem(deck)
 		y -= linespacing
 	}
+	return nil
 }
 
 // VDot makes a vertical dotted bar chart
-func (c *ChartBox) VDot(deck *generate.Deck, size float64) {
-	dlen := float64(len(c.Data) - 1)
-	ymin := zerobase(c.Zerobased, c.Minvalue)
+func (c *ChartBox) VDot(deck Renderer, size float64) error {
+	if err := c.need("dot", 1); err != nil {
+		return err
+	}
 	for i, d := range c.Data {
-		x := MapRange(float64(i), 0, dlen, c.Left, c.Right)
-		y := MapRange(d.Value, ymin, c.Maxvalue, c.Bottom, c.Top)
-		dottedvline(deck, x, c.Bottom, y, 0.25, 1, c.DataColor)
+		x := c.xpos(i)
+		y := c.ypos(d.Value)
+		startitem(deck, -1, "", d.Label, fmt.Sprintf(c.DataFormat, d.Value), d.Note)
+		dottedvline(deck, x, c.ybase(), y, 0.25, 1, c.DataColor)
 		deck.Circle(x, y, size, c.DataColor, c.Opacity)
+		enditem(deck)
 	}
+	return nil
 }
 
 // PMap makes a proportional map
-func (c *ChartBox) PMap(deck *generate.Deck, pwidth, pmlen float64, showvalues, solid bool) {
+func (c *ChartBox) PMap(deck Renderer, pwidth, pmlen float64, showvalues, solid bool) error {
+	if err := c.need("proportional map", 1); err != nil {
+		return err
+	}
 	top := c.Top
 	left := c.Left
 	right := c.Right
@@ -523,22 +1136,25 @@ func (c *ChartBox) PMap(deck *generate.Deck, pwidth, pmlen float64, showvalues,
 	hspace := 0.10
 	var ty float64
 	var textcolor string
+	theme := c.theme()
 
 	data := c.Data
 	for i, p := range pct(data) {
 		bx := (p * bl)
 		if p < 3 || float64(len(data[i].Label)) > pmlen {
 			ty = top - pwidth*1.2
-			deck.Line(x+(bx/2), ty+(textsize*1.5), x+(bx/2), top, 0.1, dotlinecolor)
+			deck.Line(x+(bx/2), ty+(textsize*1.5), x+(bx/2), top, 0.1, theme.Guide)
 		} else {
 			ty = top
 		}
-		linecolor, lineop := stdcolor(i, data[i].Note, c.DataColor, p, solid)
+		linecolor, lineop := c.stdcolor(i, data[i].Note, p, solid)
+		startitem(deck, -1, "", data[i].Label, fmt.Sprintf(format+" ("+format+"%%)", data[i].Value, p), "")
 		deck.Line(x, top, bx+x, top, pwidth, linecolor, lineop)
+		enditem(deck)
 		if lineop == 100 {
-			textcolor = "white"
+			textcolor = theme.Contrast
 		} else {
-			textcolor = "black"
+			textcolor = theme.Text
 		}
 
 		if showvalues {
@@ -548,18 +1164,18 @@ func (c *ChartBox) PMap(deck *generate.Deck, pwidth, pmlen float64, showvalues,
 
 		x += bx - hspace
 	}
+	return nil
 }
 
 // Slope makes a slope chart
-func (c *ChartBox) Slope(deck *generate.Deck, linewidth float64) {
+func (c *ChartBox) Slope(deck Renderer, linewidth float64) error {
+	if err := c.need("slope", 2); err != nil {
+		return err
+	}
 	data := c.Data
 	textsize := c.TextSize
 	format := c.DataFormat
 
-	if len(data) < 2 {
-		fmt.Fprintf(os.Stderr, "slope graphs need at least two data pointextsize")
-		return
-	}
 	ymin := zerobase(c.Zerobased, c.Minvalue)
 	top := c.Top
 	bottom := c.Bottom
@@ -587,10 +1203,14 @@ func (c *ChartBox) Slope(deck *generate.Deck, linewidth float64) {
 		v2 := data[i+1].Value
 		v1y := MapRange(v1, ymin, c.Maxvalue, bottom, top)
 		v2y := MapRange(v2, ymin, c.Maxvalue, bottom, top)
-		deck.Line(x1, bottom, x1, top, lw, "black")
-		deck.Line(x2, bottom, x2, top, lw, "black")
+		deck.Line(x1, bottom, x1, top, lw, c.theme().Line)
+		deck.Line(x2, bottom, x2, top, lw, c.theme().Line)
+		startitem(deck, -1, "", data[i].Label, fmt.Sprintf(format, v1), data[i].Note)
 		deck.Circle(x1, v1y, textsize, datacolor)
+		enditem(deck)
+		startitem(deck, -1, "", data[i+1].Label, fmt.Sprintf(format, v2), data[i+1].Note)
 		deck.Circle(x2, v2y, textsize, datacolor)
+		enditem(deck)
 		deck.Line(x1, v1y, x2, v2y, linewidth, datacolor)
 		deck.TextMid(x1, bottom-2, data[i].Label, "sans", textsize, c.LabelColor)
 		deck.TextMid(x2, bottom-2, data[i+1].Label, "sans", textsize, c.LabelColor)
@@ -610,10 +1230,14 @@ func (c *ChartBox) Slope(deck *generate.Deck, linewidth float64) {
 			bottom -= vskip
 		}
 	}
+	return nil
 }
 
 // Donut makes donut and pie charts
-func (c *ChartBox) Donut(deck *generate.Deck, psize, pwidth float64, showval, solid bool) {
+func (c *ChartBox) Donut(deck Renderer, psize, pwidth float64, showval, solid bool) error {
+	if err := c.need("donut", 1); err != nil {
+		return err
+	}
 	top := c.Top
 	left := c.Left
 	textsize := c.TextSize
@@ -632,23 +1256,30 @@ func (c *ChartBox) Donut(deck *generate.Deck, psize, pwidth float64, showval, so
 		a2 := a1 + angle
 		mid := (a1 + a2) / 2
 
-		bcolor, op := stdcolor(i, data[i].Note, c.DataColor, p, solid)
+		bcolor, op := c.stdcolor(i, data[i].Note, p, solid)
+		startitem(deck, -1, "", data[i].Label, fmt.Sprintf(c.DataFormat+" ("+c.DataFormat+"%%)", data[i].Value, p), "")
 		deck.Arc(dx, dy, psize, psize, pwidth, a1, a2, bcolor, op)
+		enditem(deck)
 		tx, ty := polar(dx, dy, psize*.85, mid*(math.Pi/180))
 		if showval {
-			deck.TextMid(tx, ty, fmt.Sprintf("%s "+c.DataFormat+"%%", data[i].Label, p), "sans", textsize, "")
+			deck.TextMid(tx, ty, fmt.Sprintf("%s "+c.DataFormat+"%%", data[i].Label, p), "sans", textsize, c.theme().Text)
 		}
 		a1 = a2
 	}
+	return nil
 }
 
 // Radial makes a radial chart
-func (c *ChartBox) Radial(deck *generate.Deck, psize, pwidth float64, showspokes, showvalues bool) {
+func (c *ChartBox) Radial(deck Renderer, psize, pwidth float64, showspokes, showvalues bool) error {
+	if err := c.need("radial", 1); err != nil {
+		return err
+	}
 	data := c.Data
 	top := c.Top
 	left := c.Left
 	textsize := c.TextSize
 	datacolor := c.DataColor
+	theme := c.theme()
 	if left < 0 {
 		left = 50.0
 	}
@@ -656,7 +1287,7 @@ func (c *ChartBox) Radial(deck *generate.Deck, psize, pwidth float64, showspokes
 	dy := top
 
 	t := topclock
-	deck.Circle(dx, dy, pwidth*2, "silver", 10)
+	deck.Circle(dx, dy, pwidth*2, theme.Guide, 10)
 	step := fullcircle / float64(len(data))
 	var color string
 	for _, d := range data {
@@ -669,22 +1300,28 @@ func (c *ChartBox) Radial(deck *generate.Deck, psize, pwidth float64, showspokes
 		} else {
 			color = datacolor
 		}
-		deck.TextMid(tx, ty, d.Label, "sans", textsize/2, "black")
+		deck.TextMid(tx, ty, d.Label, "sans", textsize/2, theme.Text)
 		if showvalues {
 			deck.TextMid(px, py-textsize/3, fmt.Sprintf(c.DataFormat, d.Value), "mono", textsize, c.LabelColor)
 		}
+		startitem(deck, -1, "", d.Label, fmt.Sprintf(c.DataFormat, d.Value), "")
 		if showspokes {
-			spokes(deck, px, py, psize/2, 0.05, int(d.Value), color)
+			spokes(deck, px, py, psize/2, 0.05, int(d.Value), color, theme.Guide)
 		} else {
 			deck.Circle(px, py, cv, color, transparency)
-			deck.Line(tx, ty, px, py, 0.05, "gray", 50)
+			deck.Line(tx, ty, px, py, 0.05, theme.Grid, 50)
 		}
+		enditem(deck)
 		t -= step
 	}
+	return nil
 }
 
 // PGrid makes a proportional grid with the specified rows and columns
-func (c *ChartBox) PGrid(deck *generate.Deck, linespacing float64, rows, cols int, showvalues bool) {
+func (c *ChartBox) PGrid(deck Renderer, linespacing float64, rows, cols int, showvalues bool) error {
+	if err := c.need("proportional grid", 1); err != nil {
+		return err
+	}
 	textsize := c.TextSize
 	data := c.Data
 	top := c.Top
@@ -696,7 +1333,7 @@ func (c *ChartBox) PGrid(deck *generate.Deck, linespacing float64, rows, cols in
 		left = 30.0
 	}
 	if rows*cols != 100 {
-		return
+		return &ArgumentError{Name: "rows*cols", Reason: fmt.Sprintf("a proportional grid has 100 cells, not %d", rows*cols)}
 	}
 	sum := 0.0
 	for _, d := range data {
@@ -742,30 +1379,40 @@ func (c *ChartBox) PGrid(deck *generate.Deck, linespacing float64, rows, cols in
 			deck.TextEnd(left+cx, y-(textsize/2), fmt.Sprintf(format, d.Value), "sans", textsize, c.ValueColor)
 		}
 	}
+	return nil
 }
 
 // axes
 
-// YAxis makes the Y axis with optional grid lines
-func (c *ChartBox) YAxis(deck *generate.Deck, min, max, step float64, gridlines bool) {
+// YAxis makes the Y axis with optional grid lines.
+// With a YScale, labels are placed at the ticks of the scale (for example, powers of ten),
+// with minor ticks between them.
+func (c *ChartBox) YAxis(deck Renderer, min, max, step float64, gridlines bool) {
 	w := c.Right - c.Left
 	textsize := c.TextSize
-	ymin := zerobase(c.Zerobased, c.Minvalue)
-	for v := min; v <= max; v += step {
-		y := MapRange(v, ymin, c.Maxvalue, c.Bottom, c.Top)
+	major, minor := c.yticks(min, max, step)
+	theme := c.theme()
+	for _, v := range major {
+		y := c.ypos(v)
 		if gridlines {
-			deck.Line(c.Left, y, c.Left+w, y, 0.05, "gray")
+			deck.Line(c.Left, y, c.Left+w, y, 0.05, theme.Grid)
 		}
 		deck.TextEnd(c.Left-2, y-(textsize/3), fmt.Sprintf(c.DataFormat, v), "sans", textsize, c.LabelColor, c.Opacity)
 	}
+	for _, v := range minor {
+		y := c.ypos(v)
+		if gridlines {
+			deck.Line(c.Left, y, c.Left+w, y, 0.025, theme.Guide)
+		}
+		deck.Line(c.Left-(textsize/2), y, c.Left, y, 0.05, theme.Grid)
+	}
 }
 
 // XLabel makes the x axis labels
-func (c *ChartBox) XLabel(deck *generate.Deck, n int) {
+func (c *ChartBox) XLabel(deck Renderer, n int) {
 	textsize := c.TextSize
-	fn := float64(len(c.Data) - 1)
 	for i, d := range c.Data {
-		x := MapRange(float64(i), 0, fn, c.Left, c.Right)
+		x := c.xpos(i)
 		if i%n == 0 {
 			deck.TextMid(x, c.Bottom-(textsize*2), d.Label, "sans", textsize, c.LabelColor, c.Opacity)
 		}
@@ -773,11 +1420,10 @@ func (c *ChartBox) XLabel(deck *generate.Deck, n int) {
 }
 
 // XStaggerLabel makes staggered x axis labels
-func (c *ChartBox) XStaggerLabel(deck *generate.Deck, n int) {
+func (c *ChartBox) XStaggerLabel(deck Renderer, n int) {
 	textsize := c.TextSize
-	fn := float64(len(c.Data) - 1)
 	for i, d := range c.Data {
-		x := MapRange(float64(i), 0, fn, c.Left, c.Right)
+		x := c.xpos(i)
 		if i%n == 0 {
 			deck.TextMid(x, c.Bottom-(textsize*2), d.Label, "sans", textsize, c.LabelColor, c.Opacity)
 		} else {
@@ -787,21 +1433,70 @@ func (c *ChartBox) XStaggerLabel(deck *generate.Deck, n int) {
 }
 
 // XRotateLabel makes rotated x axis labels
-func (c *ChartBox) XRotateLabel(deck *generate.Deck, angle float64, n int) {
+func (c *ChartBox) XRotateLabel(deck Renderer, angle float64, n int) {
 	textsize := c.TextSize
-	fn := float64(len(c.Data) - 1)
 	for i, d := range c.Data {
-		x := MapRange(float64(i), 0, fn, c.Left, c.Right)
+		x := c.xpos(i)
 		if i%n == 0 {
 			deck.TextRotate(x, c.Bottom-(textsize*2), d.Label, "", "sans", angle, textsize, c.LabelColor, c.Opacity)
 		}
 	}
 }
 
+// xlastlabel makes the last x axis label, if the interval n skips it
+func (c *ChartBox) xlastlabel(deck Renderer, angle float64, n int) {
+	i := len(c.Data) - 1
+	if n <= 1 || i <= 0 || i%n == 0 {
+		return
+	}
+	textsize := c.TextSize
+	deck.TextRotate(c.xpos(i), c.Bottom-(textsize*2), c.Data[i].Label, "", "sans", angle, textsize, c.LabelColor, c.Opacity)
+}
+
+// XAxis makes the x axis labels at numeric x values, with optional grid lines
+func (c *ChartBox) XAxis(deck Renderer, min, max, step float64, gridlines bool) {
+	textsize := c.TextSize
+	xmin, xmax := c.xrange()
+	for v := min; v <= max+(step/2); v += step {
+		x := MapRange(v, xmin, xmax, c.Left, c.Right)
+		if gridlines {
+			deck.Line(x, c.Bottom, x, c.Top, 0.05, c.theme().Grid)
+		}
+		deck.TextMid(x, c.Bottom-(textsize*2), fmt.Sprintf(c.DataFormat, v), "sans", textsize, c.LabelColor, c.Opacity)
+	}
+}
+
+// TimeAxis makes x axis labels for time x values, on calendar boundaries every n units,
+// where the unit is one of "day", "week", "month", "quarter" or "year".
+// If the unit is empty, the unit is chosen from the time span.
+// format is a time layout (for example "Jan 2006"); if empty, the default for the unit is used.
+// Quarter labels begin with the quarter (Q1, Q2, Q3, Q4).
+func (c *ChartBox) TimeAxis(deck Renderer, unit string, n int, format string, gridlines bool) {
+	textsize := c.TextSize
+	xmin, xmax := c.xrange()
+	if len(unit) == 0 {
+		unit, n = timestep(xmax-xmin, 8)
+	}
+	if len(format) == 0 {
+		format = timeformats[unit]
+	}
+	for _, t := range timeticks(unixtime(xmin), unixtime(xmax), unit, n) {
+		x := MapRange(float64(t.Unix()), xmin, xmax, c.Left, c.Right)
+		if gridlines {
+			deck.Line(x, c.Bottom, x, c.Top, 0.05, c.theme().Grid)
+		}
+		label := t.Format(format)
+		if unit == "quarter" {
+			label = fmt.Sprintf("Q%d %s", (int(t.Month())+2)/3, label)
+		}
+		deck.TextMid(x, c.Bottom-(textsize*2), label, "sans", textsize, c.LabelColor, c.Opacity)
+	}
+}
+
 // chart accessories
 
 // RegressionLine makes a regression line from a data set
-func (c *ChartBox) RegressionLine(deck *generate.Deck, size float64) {
+func (c *ChartBox) RegressionLine(deck Renderer, size float64) {
 	top := c.Top
 	left := c.Left
 	bottom := c.Bottom
@@ -810,58 +1505,79 @@ func (c *ChartBox) RegressionLine(deck *generate.Deck, size float64) {
 	x := make([]float64, len(c.Data))
 	y := make([]float64, len(c.Data))
 	for i, data := range c.Data {
-		x[i] = float64(i)
+		x[i] = c.xvalue(i)
 		y[i] = data.Value
 	}
 	m, b := dataslope(x, y)
-	dl := len(x) - 1
-	l := float64(dl)
-	x1 := x[0]
-	x2 := x[dl]
+	x1, x2 := c.xrange()
 	y1 := m*x1 + b
 	y2 := m*x2 + b
-	ymin := zerobase(c.Zerobased, c.Minvalue)
-	rx1 := MapRange(x1, 0, l, left, right)
-	rx2 := MapRange(x2, 0, l, left, right)
-	ry1 := MapRange(y1, ymin, c.Maxvalue, bottom, top)
-	ry2 := MapRange(y2, ymin, c.Maxvalue, bottom, top)
+	rx1 := MapRange(x1, x1, x2, left, right)
+	rx2 := MapRange(x2, x1, x2, left, right)
+	ry1 := c.vmap(y1, bottom, top)
+	ry2 := c.vmap(y2, bottom, top)
 	deck.Line(rx1, ry1, rx2, ry2, lw, c.DataColor, c.Opacity)
 }
 
 // Values places chart values
-func (c *ChartBox) Values(deck *generate.Deck, offset float64) {
+func (c *ChartBox) Values(deck Renderer, offset float64) {
+	c.values(deck, offset, 0, "t")
+}
+
+// values places chart values at a position: "t" (top), offset from the values,
+// "m" (middle), between the values and the baseline, or "b" (bottom), at the baseline.
+// The values of several series are placed over their bars in a label slot of the specified size (see Bar).
+func (c *ChartBox) values(deck Renderer, offset, size float64, position string) {
 	n := len(c.Data)
-	fn := float64(n - 1)
-	ymin := zerobase(c.Zerobased, c.Minvalue)
-	for i := 0; i < n; i++ {
-		v := c.Data[i].Value
-		x := MapRange(float64(i), 0, fn, c.Left, c.Right)
-		y := MapRange(v, ymin, c.Maxvalue, c.Bottom, c.Top)
-		deck.TextMid(x, y+offset, fmt.Sprintf(c.DataFormat, v), "mono", c.TextSize, c.ValueColor, c.Opacity)
+	base := c.ybase()
+	series := c.series()
+	for k, s := range series {
+		dx, _ := barslot(k, len(series), size)
+		for i := 0; i < n; i++ {
+			v := s.Values[i]
+			x := c.xpos(i) + dx
+			var y float64
+			switch position {
+			case "m":
+				y = ((c.ypos(v) + base) / 2) - (c.TextSize / 3)
+			case "b":
+				y = base + offset
+				if v < 0 {
+					y -= (offset * 2) + c.TextSize
+				}
+			default:
+				y = c.ypos(v) + offset
+				if v < 0 { // below negative values
+					y -= (offset * 2) + c.TextSize
+				}
+			}
+			deck.TextMid(x, y, fmt.Sprintf(c.DataFormat, v), "mono", c.TextSize, c.ValueColor, c.Opacity)
+		}
 	}
 }
 
 // CTitle makes a centered title
-func (c *ChartBox) CTitle(deck *generate.Deck, offset float64) {
+func (c *ChartBox) CTitle(deck Renderer, offset float64) {
 	midx := c.Left + ((c.Right - c.Left) / 2)
 	deck.TextMid(midx, c.Top+offset, c.Title, "sans", c.TextSize*2, c.DataColor, c.Opacity)
 }
 
 // Frame makes a filled frame with the specified opacity (0-100)
-func (c *ChartBox) Frame(deck *generate.Deck, opacity float64) {
+func (c *ChartBox) Frame(deck Renderer, opacity float64) {
 	w := c.Right - c.Left
 	h := c.Top - c.Bottom
 	deck.Rect(c.Left+w/2, c.Bottom+h/2, w, h, c.DataColor, opacity)
 }
 
-// Notes places notes
-func (c *ChartBox) Notes(deck *generate.Deck, position string) {
+// Notes places notes; notes that are colors (which color the data of some charts) are not placed
+func (c *ChartBox) Notes(deck Renderer, position string) {
 	textsize := c.TextSize
-	fn := float64(len(c.Data) - 1)
-	ymin := zerobase(c.Zerobased, c.Minvalue)
 	for i, data := range c.Data {
-		x := MapRange(float64(i), 0, fn, c.Left, c.Right)
-		y := MapRange(data.Value, ymin, c.Maxvalue, c.Bottom, c.Top)
+		if iscolor(data.Note) {
+			continue
+		}
+		x := c.xpos(i)
+		y := c.ypos(data.Value)
 		switch position {
 		case "c":
 			deck.TextMid(x, y, data.Note, "serif", textsize, c.LabelColor, c.Opacity)
@@ -876,17 +1592,37 @@ func (c *ChartBox) Notes(deck *generate.Deck, position string) {
 }
 
 // LineNote places a note with a horizontal line set at a value
-func (c *ChartBox) LineNote(deck *generate.Deck, v float64, s string, size float64) {
-	ymin := zerobase(c.Zerobased, c.Minvalue)
-	y := MapRange(v, ymin, c.Maxvalue, c.Bottom, c.Top)
+func (c *ChartBox) LineNote(deck Renderer, v float64, s string, size float64) {
+	y := c.ypos(v)
 	deck.Line(c.Left, y, c.Right, y, 0.1, c.DataColor, c.Opacity)
 	if len(s) > 0 {
 		deck.Text(c.Right+(size/2), y-(size/4), s, "serif", c.TextSize*0.75, c.DataColor, c.Opacity)
 	}
 }
 
+// ZeroLine makes a horizontal reference line at zero, if zero is within the chart
+func (c *ChartBox) ZeroLine(deck Renderer, size float64) {
+	ymin, ymax := c.ydomain()
+	if ymin < 0 && ymax > 0 {
+		y := c.ypos(0)
+		deck.Line(c.Left, y, c.Right, y, size, c.LabelColor, c.Opacity)
+	}
+}
+
+// HZeroLine makes a vertical reference line at zero for horizontal charts,
+// spanning the rows at the specified spacing
+func (c *ChartBox) HZeroLine(deck Renderer, size, linespacing float64) {
+	ymin, ymax := c.ydomain()
+	if ymin < 0 && ymax > 0 {
+		x := c.vmap(0, c.Left, c.Right)
+		y1 := c.Top + (linespacing / 2)
+		y2 := c.Top - (float64(len(c.Data)) * linespacing) + (linespacing / 2)
+		deck.Line(x, y1, x, y2, size, c.LabelColor, c.Opacity)
+	}
+}
+
 // Grid makes a grid
-func (c *ChartBox) Grid(deck *generate.Deck, size, step float64) {
+func (c *ChartBox) Grid(deck Renderer, size, step float64) {
 	for x := c.Left; x <= c.Right; x += step {
 		deck.Line(x, c.Bottom, x, c.Top, size, c.DataColor, c.Opacity)
 	}
@@ -898,18 +1634,26 @@ func (c *ChartBox) Grid(deck *generate.Deck, size, step float64) {
 // driver and I/O methods
 
 // NewChart initializes the settings required to make a chart
-// chartType may be one of: "line", "slope", "bar", "wbar", "hbar",
-// "volume, "scatter", "donut", "pmap", "pgrid","radial"
+// chartType may be one of: "line", "slope", "bar", "stack", "group", "wbar", "hbar", "hstack", "hpct",
+// "volume, "scatter", "donut", "pmap", "pgrid","radial", "candle", "ohlc", "histogram", "box", "hbox", "heatmap"
 func NewChart(chartType string, top, bottom, left, right float64) Settings {
 	var s Settings
 
 	switch chartType {
 	case "bar":
 		s.Flags.ShowBar = true
+	case "stack", "stacked":
+		s.Flags.ShowStackedBar = true
+	case "group", "grouped":
+		s.Flags.ShowGroupedBar = true
 	case "wbar":
 		s.Flags.ShowWBar = true
 	case "hbar":
 		s.Flags.ShowHBar = true
+	case "hstack":
+		s.Flags.ShowStackedHBar = true
+	case "hpct":
+		s.Flags.ShowPctHBar = true
 	case "donut":
 		s.Flags.ShowDonut = true
 	case "pmap":
@@ -926,6 +1670,18 @@ func NewChart(chartType string, top, bottom, left, right float64) Settings {
 		s.Flags.ShowVolume = true
 	case "slope":
 		s.Flags.ShowSlope = true
+	case "candle", "candlestick":
+		s.Flags.ShowCandle = true
+	case "ohlc":
+		s.Flags.ShowOHLC = true
+	case "histogram", "hist":
+		s.Flags.ShowHistogram = true
+	case "box", "boxplot":
+		s.Flags.ShowBox = true
+	case "hbox":
+		s.Flags.ShowHBox = true
+	case "heatmap":
+		s.Flags.ShowHeatmap = true
 	}
 	if left <= 0 {
 		left = 10
@@ -937,7 +1693,7 @@ func NewChart(chartType string, top, bottom, left, right float64) Settings {
 		top = 90
 	}
 	if bottom <= 0 {
-		bottom = 30
+		bottom = 50
 	}
 	s.Measures.Left = left
 	s.Measures.Right = right
@@ -946,97 +1702,258 @@ func NewChart(chartType string, top, bottom, left, right float64) Settings {
 	s.Measures.XLabelInterval = 1
 	s.Measures.TextSize = 1.5
 	s.Measures.LineSpacing = 2.4
+	s.Measures.UserMin = -1
+	s.Measures.UserMax = -1
 
-	s.Attributes.BackgroundColor = "white"
-	s.Attributes.DataColor = "lightsteelblue"
-	s.Attributes.LabelColor = "rgb(75,75,75)"
+	s.Attributes.BackgroundColor = lighttheme.Background
+	s.Attributes.DataColor = lighttheme.Data
+	s.Attributes.LabelColor = lighttheme.Label
 
 	return s
 }
 
 // GenerateChart makes charts according to the orientation:
-// horizontal bar or line, bar, dot, or donut volume charts
-func (s *Settings) GenerateChart(deck *generate.Deck, r io.Reader) {
+// horizontal bar or line, bar, dot, or donut volume charts.
+// Errors reading the data, in the settings, or drawing the chart are returned;
+// with the Strict flag, values that are not numbers are errors.
+func (s *Settings) GenerateChart(deck Renderer, r io.Reader) error {
 	f := s.Flags
 	m := s.Measures
 	a := s.Attributes
+	if len(a.FacetColumn) > 0 {
+		return s.multiples(deck, r)
+	}
 	var chart ChartBox
 	var err error
-	if f.ReadCSV {
-		chart, err = ReadCSV(r, a.CSVCols)
-	} else {
-		chart, err = ReadTSV(r)
+	switch {
+	case f.ShowHistogram && f.ReadCSV:
+		column := a.CSVCols
+		if i := strings.LastIndex(column, ","); i >= 0 { // the value column of label,value
+			column = column[i+1:]
+		}
+		chart, err = readcsvvalues(r, column, f.Strict)
+	case f.ShowHistogram:
+		chart, err = readvalues(r, f.Strict)
+	case f.ShowHeatmap && f.ReadCSV:
+		chart, err = readcsvmatrix(r, a.CSVCols, f.Strict)
+	case f.ReadCSV:
+		csvcols := a.CSVCols
+		if len(csvcols) == 0 && (f.ShowCandle || f.ShowOHLC) {
+			csvcols = ohlccols
+		}
+		chart, err = readcsv(r, csvcols, f.Strict)
+	default:
+		chart, err = readtsv(r, f.Strict)
 	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		return
+		return err
+	}
+	if f.ShowBox || f.ShowHBox {
+		chart = groupsamples(chart)
+	}
+	if len(a.ChartTitle) > 0 {
+		chart.Title = a.ChartTitle
+	}
+	if len(a.DataFmt) > 0 {
+		chart.DataFormat = a.DataFmt
 	}
-	clow, chigh, condcolor, err := parsecondition(a.DataCondition)
+	chart.ColorScale, err = NewColorScale(a.DataCondition)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
-		return
+		return err
+	}
+	if len(a.YScale) > 0 {
+		chart.YScale, err = NewScale(a.YScale)
+		if err != nil {
+			return err
+		}
+	}
+	theme, err := a.theme()
+	if err != nil {
+		return err
+	}
+	chart.SetTheme(theme)
+	var hv float64
+	var hlabel string
+	if len(a.HLine) > 0 {
+		if hv, hlabel, err = hline(a.HLine); err != nil {
+			return err
+		}
+	}
+	unit, nunit, err := timeunit(a.XTimeUnit)
+	if err != nil {
+		return err
+	}
+	minmax, err := s.whiskers()
+	if err != nil {
+		return err
+	}
+	if m.Left > 0 {
+		chart.Left = m.Left
+	}
+	if m.Right > 0 {
+		chart.Right = m.Right
+	}
+	if m.Top > 0 {
+		chart.Top = m.Top
+	}
+	if m.Bottom > 0 {
+		chart.Bottom = m.Bottom
+	}
+	chart.XValue = f.XValue
+	chart.XTime = f.XTime
+	if f.XTime {
+		chart.settimes()
+	}
+	if f.ShowStackedBar || f.ShowStackedHBar {
+		chart.Maxvalue = chart.StackMax()
+	}
+	s.datarange(&chart)
+	var xmin, xmax, xstep float64
+	if f.XValue {
+		if a.XAxisR == "" {
+			xmin, xmax, xstep = xtickrange(chart.XMinvalue, chart.XMaxvalue, 5)
+		} else {
+			xmin, xmax, xstep = yrange(a.XAxisR)
+			chart.XMinvalue, chart.XMaxvalue = xmin, xmax
+		}
 	}
-	chart.DataColor = a.DataColor
-	chart.ValueColor = a.ValueColor
-	chart.LabelColor = a.LabelColor
 	switch {
-	case f.ShowVDot:
-		chart.VDot(deck, m.LineWidth)
+	case f.ShowCandle || f.ShowOHLC:
+		err = s.candlestick(deck, &chart)
+	case f.ShowHistogram:
+		err = s.histogram(deck, &chart)
+	case f.ShowHBox:
+		err = s.hboxplot(deck, &chart)
+	case f.ShowHeatmap:
+		err = s.heatmap(deck, &chart)
 	case f.ShowHBar:
-		chart.ConditionalHBar(deck, m.BarWidth, m.LineSpacing, clow, chigh, condcolor)
+		err = chart.ConditionalHBar(deck, m.BarWidth, m.LineSpacing, smallest, largest, "")
+		chart.HZeroLine(deck, 0.1, m.LineSpacing)
+	case f.ShowStackedHBar:
+		err = chart.StackedHBar(deck, m.BarWidth, m.LineSpacing, false, f.ShowPercentage)
+	case f.ShowPctHBar:
+		err = chart.StackedHBar(deck, m.BarWidth, m.LineSpacing, true, f.ShowPercentage)
 	case f.ShowHDot:
-		chart.HDot(deck, m.LineWidth, m.LineSpacing)
+		err = chart.HDot(deck, m.LineWidth, m.LineSpacing)
+		chart.HZeroLine(deck, 0.1, m.LineSpacing)
 	case f.ShowWBar:
-		chart.WBar(deck, m.LineSpacing, f.ShowValues, f.ShowPercentage)
+		err = chart.WBar(deck, m.LineSpacing, f.ShowValues, f.ShowPercentage)
+		chart.HZeroLine(deck, 0.1, m.LineSpacing)
 	case f.ShowDonut:
-		chart.Donut(deck, m.PSize, m.PWidth, f.ShowValues, f.SolidPMap)
+		err = chart.Donut(deck, m.PSize, m.PWidth, f.ShowValues, f.SolidPMap)
 	case f.ShowPMap:
-		chart.PMap(deck, m.PWidth, m.PSize, f.ShowValues, f.SolidPMap)
+		err = chart.PMap(deck, m.PWidth, float64(m.PMapLength), f.ShowValues, f.SolidPMap)
 	case f.ShowPGrid:
-		chart.PGrid(deck, m.LineSpacing, 10, 10, f.ShowValues)
+		err = chart.PGrid(deck, m.LineSpacing, 10, 10, f.ShowValues)
 	case f.ShowRadial:
-		chart.Radial(deck, m.PSize, m.PWidth, f.ShowSpokes, f.ShowValues)
+		err = chart.Radial(deck, m.PSize, m.PWidth, f.ShowSpokes, f.ShowValues)
 	case f.ShowSlope:
-		chart.Slope(deck, m.LineWidth)
+		err = chart.Slope(deck, m.LineWidth)
 	default:
 		if m.BarWidth == 0 {
 			m.BarWidth = (chart.Right - chart.Left) / float64(len(chart.Data)+1)
 		}
-		chart.ConditionalBar(deck, m.BarWidth, clow, chigh, condcolor)
+		switch {
+		case f.ShowStackedBar:
+			err = chart.StackedBar(deck, m.BarWidth)
+		case f.ShowGroupedBar:
+			err = chart.GroupedBar(deck, m.BarWidth)
+		case f.ShowVDot:
+			err = chart.VDot(deck, m.LineWidth)
+		case f.ShowBox:
+			err = chart.BoxPlot(deck, m.BarWidth, minmax)
+		default:
+			err = chart.ConditionalBar(deck, m.BarWidth, smallest, largest, "")
+		}
 
-		if f.ShowScatter {
-			chart.ConditionalScatter(deck, m.LineWidth, clow, chigh, condcolor)
+		if f.ShowScatter && err == nil {
+			err = chart.ConditionalScatter(deck, m.LineWidth, smallest, largest, "")
 		}
-		if f.ShowLine {
-			chart.ConditionalLine(deck, m.LineWidth, clow, chigh, condcolor)
+		if f.ShowLine && err == nil {
+			err = chart.ConditionalLine(deck, m.LineWidth, smallest, largest, "")
 		}
-		if f.ShowVolume {
+		if f.ShowVolume && err == nil {
 			op := chart.Opacity
 			chart.Opacity = m.VolumeOpacity
-			chart.Area(deck)
+			err = chart.Area(deck)
 			chart.Opacity = op
 		}
+		if f.ShowRegressionLine && err == nil {
+			rline := chart
+			rline.DataColor = theme.Value
+			if len(a.RegressionLineColor) > 0 {
+				rline.DataColor = a.RegressionLineColor
+			}
+			rline.RegressionLine(deck, m.LineWidth)
+		}
+		chart.ZeroLine(deck, 0.1)
+		if f.ShowValues && !f.ShowStackedBar && !f.ShowBox {
+			chart.values(deck, chart.TextSize/2, m.BarWidth, a.ValuePosition)
+		}
+		if f.ShowNote {
+			chart.Notes(deck, a.NoteLocation)
+		}
+		if len(a.HLine) > 0 {
+			note := chart
+			note.DataColor = theme.Line
+			note.LineNote(deck, hv, hlabel, chart.TextSize)
+		}
 
 		if f.ShowTitle {
-			chart.DataColor = "black"
-			chart.CTitle(deck, 5)
+			title := chart
+			title.DataColor = theme.Title
+			title.CTitle(deck, 5)
 		}
 		if f.ShowFrame {
-			chart.Frame(deck, 10)
+			frame := chart
+			frame.DataColor = theme.Grid
+			if len(a.FrameColor) > 0 {
+				frame.DataColor = a.FrameColor
+			}
+			frame.Frame(deck, 10)
 		}
 		if m.XLabelInterval != 0 {
-			chart.XRotateLabel(deck, m.XLabelRotation, m.XLabelInterval)
+			switch {
+			case f.XTime:
+				chart.TimeAxis(deck, unit, nunit, a.XTimeFormat, f.ShowGrid)
+			case f.XValue:
+				chart.XAxis(deck, xmin, xmax, xstep, f.ShowGrid)
+			case f.ShowXstagger:
+				chart.XStaggerLabel(deck, 2)
+			default:
+				chart.XRotateLabel(deck, m.XLabelRotation, m.XLabelInterval)
+				if f.ShowXLast {
+					chart.xlastlabel(deck, m.XLabelRotation, m.XLabelInterval)
+				}
+			}
 		}
 		if f.ShowAxis {
 			var ymin, ymax, ystep float64
 			if a.YAxisR == "" {
 				ymin, ymax, ystep = cyrange(zerobase(chart.Zerobased, chart.Minvalue), chart.Maxvalue, 5)
+				if ymin < 0 { // label zero and the steps around it
+					ymin, ymax, ystep = xtickrange(ymin, chart.Maxvalue, 8)
+				}
 			} else {
 				ymin, ymax, ystep = yrange(a.YAxisR)
 			}
 			chart.YAxis(deck, ymin, ymax, ystep, f.ShowGrid)
 		}
 	}
+	if err != nil {
+		return err
+	}
+	if len(a.Legend) > 0 {
+		chart.Legend(deck, a.Legend, a.LegendFlow, a.LegendSwatch)
+	}
+	colorbar := a.ColorBar
+	if len(colorbar) == 0 && f.ShowHeatmap { // heatmaps need their scale
+		colorbar = "right"
+	}
+	if len(colorbar) > 0 && colorbar != "none" {
+		chart.ColorBar(deck, colorbar)
+	}
+	return nil
 }
 
 // helper functions
@@ -1080,7 +1997,7 @@ func dataslope(x, y []float64) (float64, float64) {
 }
 
 // dottedvline makes dotted vertical line, using circles, with specified step
-func dottedvline(deck *generate.Deck, x, y1, y2, dotsize, step float64, color string) {
+func dottedvline(deck Renderer, x, y1, y2, dotsize, step float64, color string) {
 
 	if y1 < y2 { // positive
 		for y := y1; y <= y2; y += step {
@@ -1094,7 +2011,7 @@ func dottedvline(deck *generate.Deck, x, y1, y2, dotsize, step float64, color st
 }
 
 // dottedhline makes a dotted horizontal line, using circles with specified step and separation
-func dottedhline(deck *generate.Deck, x1, y, x2, dotsize, step float64, color string) {
+func dottedhline(deck Renderer, x1, y, x2, dotsize, step float64, color string) {
 	for x := x1; x < x2; x += step {
 		deck.Circle(x, y, dotsize, color)
 		x += step
@@ -1103,7 +2020,7 @@ func dottedhline(deck *generate.Deck, x1, y, x2, dotsize, step float64, color st
 
 // conditionalcolor chooses between two colors when the value falls between min and max
 func conditionalcolor(value, min, max float64, trueColor, falseColor string) string {
-	if value <= max && value >= min {
+	if len(trueColor) > 0 && value <= max && value >= min {
 		return trueColor
 	}
 	return falseColor
@@ -1123,10 +2040,12 @@ func pct(data []NameValue) []float64 {
 	return p
 }
 
-// stdcolor uses either the standard color (cycling through a list) or specified color and opacity
-func stdcolor(i int, dcolor, color string, op float64, solid bool) (string, float64) {
-	if color == "std" {
-		return blue7[i%len(blue7)], 100
+// stdcolor uses either the standard colors (cycling through the palette named by the data color,
+// "std" for the palette of the theme), the specified color, or the data color and opacity
+func (c *ChartBox) stdcolor(i int, dcolor string, op float64, solid bool) (string, float64) {
+	color := c.DataColor
+	if palette := c.palette(color); len(palette) > 0 {
+		return palette[i%len(palette)], 100
 	}
 	if len(dcolor) > 0 {
 		if solid {
@@ -1137,6 +2056,92 @@ func stdcolor(i int, dcolor, color string, op float64, solid bool) (string, floa
 	return color, op
 }
 
+// xvalue returns the x value of the i-th data point:
+// its numeric label in x value mode, otherwise its index
+func (c *ChartBox) xvalue(i int) float64 {
+	if c.XValue || c.XTime {
+		return c.Data[i].X
+	}
+	return float64(i)
+}
+
+// xrange returns the range of x values
+func (c *ChartBox) xrange() (float64, float64) {
+	if c.XValue || c.XTime {
+		return c.XMinvalue, c.XMaxvalue
+	}
+	return 0, float64(len(c.Data) - 1)
+}
+
+// xpos returns the horizontal position of the i-th data point
+func (c *ChartBox) xpos(i int) float64 {
+	xmin, xmax := c.xrange()
+	if xmin == xmax { // one point, or all at the same x
+		return (c.Left + c.Right) / 2
+	}
+	return MapRange(c.xvalue(i), xmin, xmax, c.Left, c.Right)
+}
+
+// stacksums returns the sum of the series values at each of n labels
+func stacksums(series []Series, n int) []float64 {
+	sums := make([]float64, n)
+	for _, s := range series {
+		for i := 0; i < n && i < len(s.Values); i++ {
+			sums[i] += s.Values[i]
+		}
+	}
+	return sums
+}
+
+// segmentlegend makes a row of color swatches and series names, at the left of the chart
+func (c *ChartBox) segmentlegend(deck Renderer, y float64, series []Series) {
+	if len(series) < 2 {
+		return
+	}
+	entries := make([]LegendEntry, len(series))
+	for k, s := range series {
+		color, _ := c.stdcolor(k, s.Color, c.Opacity, true)
+		entries[k] = LegendEntry{Label: s.Name, Color: color}
+	}
+	c.drawlegend(deck, c.Left, y, entries, true, "block", true)
+}
+
+// item begins the shapes of the value of the k-th series at label i, if the renderer is an Annotator.
+// The series is named only if there are several.
+func (c *ChartBox) item(deck Renderer, k int, s Series, i int) {
+	name := s.Name
+	if len(c.Series) < 2 {
+		k, name = -1, ""
+	}
+	startitem(deck, k, name, c.Data[i].Label, fmt.Sprintf(c.DataFormat, s.Values[i]), c.Data[i].Note)
+}
+
+// series returns the data series to draw, with their colors filled in.
+// Without explicit series, the data values are drawn in the data color.
+func (c *ChartBox) series() []Series {
+	if len(c.Series) == 0 {
+		values := make([]float64, len(c.Data))
+		for i, d := range c.Data {
+			values[i] = d.Value
+		}
+		return []Series{{Name: c.Title, Color: c.DataColor, Values: values}}
+	}
+	series := make([]Series, len(c.Series))
+	copy(series, c.Series)
+	for i := range series {
+		if len(series[i].Color) == 0 {
+			palette := c.theme().Series
+			series[i].Color = palette[i%len(palette)]
+		}
+		if len(series[i].Values) < len(c.Data) { // pad short series
+			v := make([]float64, len(c.Data))
+			copy(v, series[i].Values)
+			series[i].Values = v
+		}
+	}
+	return series
+}
+
 // polar converts polar to Cartesian coordinates
 func polar(x, y, r, t float64) (float64, float64) {
 	px := x + r*math.Cos(t)
@@ -1154,36 +2159,41 @@ func datasum(data []NameValue) float64 {
 }
 
 // spokes makes the points and lines like spokes on a wheel
-func spokes(deck *generate.Deck, cx, cy, r, spokesize float64, n int, color string) {
+func spokes(deck Renderer, cx, cy, r, spokesize float64, n int, color, spokecolor string) {
 	t := topclock
 	step := fullcircle / float64(n)
 	for i := 0; i < n; i++ {
 		px, py := polar(cx, cy, r, t)
-		deck.Line(cx, cy, px, py, spokesize, "lightgray")
+		deck.Line(cx, cy, px, py, spokesize, spokecolor)
 		deck.Circle(px, py, 0.5, color)
 		t -= step
 	}
 }
 
-// parsecondition parses the expression low,high,color. For example "0,10,red"
-// means color the data red if the value is between 0 and 10.
-func parsecondition(s string) (float64, float64, string, error) {
-	if len(s) == 0 {
-		return smallest, largest, "", nil
+// hline parses the value and optional label of a horizontal line (value,label)
+func hline(s string) (float64, string, error) {
+	vs, label, _ := strings.Cut(s, ",")
+	v, err := strconv.ParseFloat(strings.TrimSpace(vs), 64)
+	if err != nil {
+		return 0, "", &ArgumentError{Name: s, Reason: "bad horizontal line (use value,label)"}
 	}
-	cs := strings.Split(s, ",")
-	if len(cs) != 3 {
-		return smallest, largest, "", fmt.Errorf("%s bad condition", s)
+	return v, strings.TrimSpace(label), nil
+}
+
+// datarange sets the data range of the chart from the settings:
+// with DataMinimum, the range begins at the data minimum instead of zero,
+// and UserMin and UserMax, unless -1, replace the minimum and maximum
+func (s *Settings) datarange(c *ChartBox) {
+	if s.DataMinimum {
+		c.Zerobased = false
 	}
-	low, err := strconv.ParseFloat(cs[0], 64)
-	if err != nil {
-		return smallest, largest, "", err
+	if s.UserMin != -1 {
+		c.Minvalue = s.UserMin
+		c.Zerobased = false
 	}
-	high, err := strconv.ParseFloat(cs[1], 64)
-	if err != nil {
-		return smallest, largest, "", err
+	if s.UserMax != -1 {
+		c.Maxvalue = s.UserMax
 	}
-	return low, high, cs[2], nil
 }
 
 // yrange parses the min, max, step for axis labels
@@ -1196,6 +2206,96 @@ func yrange(s string) (float64, float64, float64) {
 	return min, max, step
 }
 
+// xtickrange computes rounded min, max, step for axis labels within the range
+// of min to max, using steps of 1, 2 or 5 times a power of ten, given the number of labels
+func xtickrange(min, max float64, n int) (float64, float64, float64) {
+	span := max - min
+	if span <= 0 || n < 1 {
+		return min, max, 1
+	}
+	step := math.Pow10(int(math.Floor(math.Log10(span / float64(n)))))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if span/(step*m) <= float64(n) {
+			step *= m
+			break
+		}
+	}
+	lo := math.Ceil(min/step) * step
+	if lo == 0 { // not negative zero
+		lo = 0
+	}
+	return lo, math.Floor(max/step) * step, step
+}
+
+// unixtime converts seconds since the Unix epoch to UTC time
+func unixtime(sec float64) time.Time {
+	return time.Unix(int64(sec), 0).UTC()
+}
+
+// timestep chooses the calendar unit and count for a time span (in seconds),
+// making at most the specified number of labels
+func timestep(span float64, labels int) (string, int) {
+	for _, ts := range timesteps {
+		if span/ts.seconds <= float64(labels) {
+			return ts.unit, ts.n
+		}
+	}
+	last := timesteps[len(timesteps)-1]
+	return last.unit, last.n
+}
+
+// timeunit parses a time unit with an optional count, for example "month" or "6month";
+// an empty unit is chosen from the span of the axis
+func timeunit(s string) (string, int, error) {
+	unit := strings.TrimLeft(s, "0123456789")
+	n, err := strconv.Atoi(s[:len(s)-len(unit)])
+	if err != nil || n < 1 {
+		n = 1
+	}
+	switch unit {
+	case "", "day", "week", "month", "quarter", "year":
+		return unit, n, nil
+	}
+	return "", 0, &ArgumentError{Name: s, Reason: "unknown time unit (use day, week, month, quarter or year, with an optional count)"}
+}
+
+// timeticks returns the times on calendar boundaries between min and max, every n units
+func timeticks(min, max time.Time, unit string, n int) []time.Time {
+	if n < 1 {
+		n = 1
+	}
+	y, m, d := min.Date()
+	var t time.Time
+	var next func(time.Time) time.Time
+	switch unit {
+	case "day":
+		t = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		next = func(t time.Time) time.Time { return t.AddDate(0, 0, n) }
+	case "week": // weeks begin on Monday
+		t = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		t = t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+		next = func(t time.Time) time.Time { return t.AddDate(0, 0, 7*n) }
+	case "month":
+		t = time.Date(y, ((m-1)/time.Month(n))*time.Month(n)+1, 1, 0, 0, 0, 0, time.UTC)
+		next = func(t time.Time) time.Time { return t.AddDate(0, n, 0) }
+	case "quarter":
+		t = time.Date(y, ((m-1)/time.Month(3*n))*time.Month(3*n)+1, 1, 0, 0, 0, 0, time.UTC)
+		next = func(t time.Time) time.Time { return t.AddDate(0, 3*n, 0) }
+	case "year":
+		t = time.Date(y-(y%n), 1, 1, 0, 0, 0, 0, time.UTC)
+		next = func(t time.Time) time.Time { return t.AddDate(n, 0, 0) }
+	default:
+		return nil
+	}
+	var ticks []time.Time
+	for ; !t.After(max); t = next(t) {
+		if !t.Before(min) {
+			ticks = append(ticks, t)
+		}
+	}
+	return ticks
+}
+
 // cyrange computes "optimal" min, max, step for axis labels
 // rounding the max to the appropriate number, given the number of labels
 func cyrange(min, max float64, n int) (float64, float64, float64) {
@@ -0,0 +1,140 @@
+package dchart2
+
+import "testing"
+
+const testdata = "# Test\na\t10\nb\t20\nc\t5\n"
+
+const negativedata = "# Negative\na\t10\nb\t-20\nc\t5\n"
+
+func TestBar(t *testing.T) {
+	for _, data := range []string{testdata, negativedata} {
+		c := readchart(t, data)
+		r := &recorder{}
+		if err := c.Bar(r, 2); err != nil {
+			t.Fatal(err)
+		}
+		if n := r.count("line"); n != 3 {
+			t.Errorf("got %d bars, want 3", n)
+		}
+		r.checkfinite(t)
+	}
+}
+
+func TestLine(t *testing.T) {
+	for _, data := range []string{testdata, negativedata} {
+		c := readchart(t, data)
+		r := &recorder{}
+		if err := c.Line(r, 0.2); err != nil {
+			t.Fatal(err)
+		}
+		if n := r.count("line"); n != 2 {
+			t.Errorf("got %d segments, want 2", n)
+		}
+		r.checkfinite(t)
+	}
+}
+
+func TestLineNeedsTwoPoints(t *testing.T) {
+	c := readchart(t, "a\t5\n")
+	if err := c.Line(&recorder{}, 0.2); err == nil {
+		t.Error("a line of one point is not an error")
+	}
+}
+
+func TestScatter(t *testing.T) {
+	for _, data := range []string{testdata, negativedata} {
+		c := readchart(t, data)
+		r := &recorder{}
+		if err := c.Scatter(r, 1); err != nil {
+			t.Fatal(err)
+		}
+		if n := r.count("circle"); n != 3 {
+			t.Errorf("got %d dots, want 3", n)
+		}
+		r.checkfinite(t)
+	}
+}
+
+func TestDonut(t *testing.T) {
+	c := readchart(t, testdata)
+	r := &recorder{}
+	if err := c.Donut(r, 40, 5, true, false); err != nil {
+		t.Fatal(err)
+	}
+	if n := r.count("arc"); n != 3 {
+		t.Fatalf("got %d arcs, want 3", n)
+	}
+	// the arcs go around the circle
+	last := r.ops[0]
+	for _, o := range r.ops {
+		if o.kind == "arc" {
+			last = o
+		}
+	}
+	if a2 := last.args[6]; a2 < 359.99 || a2 > 360.01 {
+		t.Errorf("arcs end at %v degrees, want 360", a2)
+	}
+	r.checkfinite(t)
+}
+
+func TestEmptyData(t *testing.T) {
+	var c ChartBox
+	if err := c.Bar(&recorder{}, 2); err == nil {
+		t.Error("a bar chart of no data is not an error")
+	}
+}
+
+func TestOnePoint(t *testing.T) {
+	c := readchart(t, "a\t5\n")
+	r := &recorder{}
+	if err := c.Scatter(r, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Bar(r, 2); err != nil {
+		t.Fatal(err)
+	}
+	r.checkfinite(t)
+	if x := r.ops[1].args[0]; x != (c.Left+c.Right)/2 {
+		t.Errorf("one point is at x %v, want the middle", x)
+	}
+}
+
+func TestSameXValue(t *testing.T) {
+	c := readchart(t, "3\t5\n3\t7\n")
+	c.XValue = true
+	r := &recorder{}
+	if err := c.Scatter(r, 1); err != nil {
+		t.Fatal(err)
+	}
+	r.checkfinite(t)
+}
+
+const multidata = "# Quarters\tA\tB\nQ1\t10\t5\nQ2\t20\t-5\nQ3\t0\t0\n"
+
+func TestStackedBar(t *testing.T) {
+	c := readchart(t, multidata)
+	r := &recorder{}
+	if err := c.StackedBar(r, 2); err != nil {
+		t.Fatal(err)
+	}
+	if n := r.count("line"); n != 6 {
+		t.Errorf("got %d segments, want 6", n)
+	}
+	r.checkfinite(t)
+}
+
+func TestGroupedBar(t *testing.T) {
+	c := readchart(t, multidata)
+	r := &recorder{}
+	if err := c.GroupedBar(r, 2); err != nil {
+		t.Fatal(err)
+	}
+	if n := r.count("line"); n != 6 {
+		t.Fatalf("got %d bars, want 6", n)
+	}
+	// the bars of a label are side by side
+	if a, b := r.ops[1].args[0], r.ops[10].args[0]; b-a != 1 {
+		t.Errorf("the bars of the first label are at x %v and %v", a, b)
+	}
+	r.checkfinite(t)
+}
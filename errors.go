@@ -0,0 +1,68 @@
+package dchart2
+
+import "fmt"
+
+// DataError reports that a chart does not have enough data
+type DataError struct {
+	Chart      string
+	Need, Have int
+}
+
+// ConditionError reports a data condition that cannot be used, such as
+// a badly formed color scale, or a range whose minimum exceeds its maximum
+type ConditionError struct {
+	Condition string
+	Reason    string
+}
+
+// ParseError reports input that cannot be read, at a line of the input
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+// ArgumentError reports a chart argument that cannot be used
+type ArgumentError struct {
+	Name   string
+	Reason string
+}
+
+func (e *DataError) Error() string {
+	return fmt.Sprintf("%s charts need at least %d data points, have %d", e.Chart, e.Need, e.Have)
+}
+
+func (e *ConditionError) Error() string {
+	if len(e.Reason) == 0 {
+		return fmt.Sprintf("%s bad condition", e.Condition)
+	}
+	return fmt.Sprintf("%s bad condition (%s)", e.Condition, e.Reason)
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ArgumentError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Name, e.Reason)
+}
+
+// need returns a DataError if the chart has fewer than n data points
+func (c *ChartBox) need(chart string, n int) error {
+	if len(c.Data) < n {
+		return &DataError{Chart: chart, Need: n, Have: len(c.Data)}
+	}
+	return nil
+}
+
+// condition returns a ConditionError if the range of a condition is reversed
+func condition(cmin, cmax float64, color string) error {
+	if cmin > cmax {
+		return &ConditionError{Condition: fmt.Sprintf("%v,%v,%s", cmin, cmax, color), Reason: "minimum exceeds maximum"}
+	}
+	return nil
+}
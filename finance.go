@@ -0,0 +1,203 @@
+package dchart2
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ohlccols are the columns of financial CSV data
+const ohlccols = "Date,Open,High,Low,Close,Volume"
+
+// priceseries returns the values of the series with the name (ignoring case),
+// or if no series has the name, of the k-th series
+func (c *ChartBox) priceseries(name string, k int) ([]float64, bool) {
+	for _, s := range c.Series {
+		if strings.EqualFold(s.Name, name) {
+			return s.Values, true
+		}
+	}
+	if k < len(c.Series) {
+		return c.Series[k].Values, true
+	}
+	return nil, false
+}
+
+// ohlc returns the open, high, low and close series of financial data:
+// the series with those names, or else the first four series, in that order
+func (c *ChartBox) ohlc() ([]float64, []float64, []float64, []float64, error) {
+	if err := c.need("candlestick", 1); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if len(c.Series) < 4 {
+		return nil, nil, nil, nil, &ArgumentError{Name: "series", Reason: fmt.Sprintf("candlestick charts need open, high, low and close series, have %d", len(c.Series))}
+	}
+	var prices [4][]float64
+	for k, name := range []string{"open", "high", "low", "close"} {
+		prices[k], _ = c.priceseries(name, k)
+		if len(prices[k]) < len(c.Data) {
+			return nil, nil, nil, nil, &DataError{Chart: "candlestick", Need: len(c.Data), Have: len(prices[k])}
+		}
+	}
+	return prices[0], prices[1], prices[2], prices[3], nil
+}
+
+// PriceRange returns the lowest low and the highest high of financial data
+// (the low and high series, see Candlestick), or if it has no prices,
+// Minvalue and Maxvalue
+func (c *ChartBox) PriceRange() (float64, float64) {
+	_, high, low, _, err := c.ohlc()
+	if err != nil {
+		return c.Minvalue, c.Maxvalue
+	}
+	min, max := largest, smallest
+	for i := range c.Data {
+		min = math.Min(min, low[i])
+		max = math.Max(max, high[i])
+	}
+	return min, max
+}
+
+// Candlestick makes a candlestick chart of financial data, with the series
+// open, high, low and close (for example read with the columns Date,Open,High,Low,Close),
+// found by name, or else the first four series in that order.
+// Each label has a body of the specified width from the open to the close,
+// and a wick from the low to the high, in the up color of the theme if the close
+// is at least the open, otherwise the down color. With ohlc, each label has a tick
+// from the low to the high instead, with the open on the left and the close on the right.
+// Prices are mapped between Minvalue and Maxvalue (see PriceRange).
+func (c *ChartBox) Candlestick(deck Renderer, size float64, ohlc bool) error {
+	op, hi, lo, cl, err := c.ohlc()
+	if err != nil {
+		return err
+	}
+	theme := c.theme()
+	format := c.DataFormat
+	for i, d := range c.Data {
+		x := c.xpos(i)
+		yo, yh, yl, yc := c.ypos(op[i]), c.ypos(hi[i]), c.ypos(lo[i]), c.ypos(cl[i])
+		color := theme.Up
+		if cl[i] < op[i] {
+			color = theme.Down
+		}
+		value := fmt.Sprintf("open "+format+" high "+format+" low "+format+" close "+format, op[i], hi[i], lo[i], cl[i])
+		startitem(deck, -1, "", d.Label, value, d.Note)
+		if ohlc {
+			lw := size / 4
+			deck.Line(x, yl, x, yh, lw, color, c.Opacity)
+			deck.Line(x-(size/2), yo, x, yo, lw, color, c.Opacity)
+			deck.Line(x, yc, x+(size/2), yc, lw, color, c.Opacity)
+		} else {
+			deck.Line(x, yl, x, yh, size/8, color, c.Opacity)
+			h := math.Max(math.Abs(yc-yo), 0.1) // a line if the open is the close
+			deck.Rect(x, (yo+yc)/2, size, h, color, c.Opacity)
+		}
+		enditem(deck)
+	}
+	return nil
+}
+
+// VolumeBar makes a bar chart of the volume of financial data (the volume series,
+// or else the fifth series),
+// from zero to the largest volume, with bars of the specified width in the
+// up or down color of the label, like Candlestick
+func (c *ChartBox) VolumeBar(deck Renderer, size float64) error {
+	op, _, _, cl, err := c.ohlc()
+	if err != nil {
+		return err
+	}
+	volume, ok := c.priceseries("volume", 4)
+	if !ok {
+		return &ArgumentError{Name: "series", Reason: "volume charts need a volume series"}
+	}
+	if len(volume) < len(c.Data) {
+		return &DataError{Chart: "volume", Need: len(c.Data), Have: len(volume)}
+	}
+	max := 0.0
+	for _, v := range volume {
+		max = math.Max(max, v)
+	}
+	theme := c.theme()
+	for i, d := range c.Data {
+		x := c.xpos(i)
+		y := c.Bottom
+		if max > 0 {
+			y = MapRange(volume[i], 0, max, c.Bottom, c.Top)
+		}
+		color := theme.Up
+		if cl[i] < op[i] {
+			color = theme.Down
+		}
+		startitem(deck, -1, "volume", d.Label, fmt.Sprintf("%.0f", volume[i]), d.Note)
+		deck.Line(x, c.Bottom, x, y, size, color, c.Opacity)
+		enditem(deck)
+	}
+	return nil
+}
+
+// candlestick makes a candlestick chart, with a volume panel below the prices
+// if its height (a percentage of the chart) is not zero, and the time axis
+// at the bottom of the chart, shared by the panels. Labels that are dates
+// are placed at their times, with calendar labels.
+func (s *Settings) candlestick(deck Renderer, c *ChartBox) error {
+	f := s.Flags
+	m := s.Measures
+	a := s.Attributes
+	if _, _, _, _, err := c.ohlc(); err != nil {
+		return err
+	}
+	if !c.XTime && c.datetimes() {
+		c.XTime = true
+		c.settimes()
+	}
+	c.Minvalue, c.Maxvalue = c.PriceRange()
+	c.Zerobased = false
+	s.datarange(c)
+
+	size := m.BarWidth
+	if size == 0 {
+		size = (c.Right - c.Left) / float64(len(c.Data)+1) * 0.6
+	}
+	prices := *c
+	if _, ok := c.priceseries("volume", 4); ok && m.VolumePanel > 0 {
+		h := (c.Top - c.Bottom) * m.VolumePanel / 100
+		volume := *c
+		volume.Top = c.Bottom + h
+		prices.Bottom = volume.Top + c.TextSize*2
+		if err := volume.VolumeBar(deck, size); err != nil {
+			return err
+		}
+	}
+	if err := prices.Candlestick(deck, size, f.ShowOHLC); err != nil {
+		return err
+	}
+	if f.ShowTitle {
+		title := prices
+		title.DataColor = c.theme().Title
+		title.CTitle(deck, 5)
+	}
+	if f.ShowAxis {
+		var ymin, ymax, ystep float64
+		if a.YAxisR == "" {
+			ymin, ymax, ystep = xtickrange(prices.Minvalue, prices.Maxvalue, 5)
+		} else {
+			ymin, ymax, ystep = yrange(a.YAxisR)
+		}
+		prices.YAxis(deck, ymin, ymax, ystep, f.ShowGrid)
+	}
+	if m.XLabelInterval != 0 {
+		if c.XTime {
+			unit, n, err := timeunit(a.XTimeUnit)
+			if err != nil {
+				return err
+			}
+			c.TimeAxis(deck, unit, n, a.XTimeFormat, f.ShowGrid)
+		} else {
+			c.XRotateLabel(deck, m.XLabelRotation, m.XLabelInterval)
+			if f.ShowXLast {
+				c.xlastlabel(deck, m.XLabelRotation, m.XLabelInterval)
+			}
+		}
+	}
+	return nil
+}
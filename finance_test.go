@@ -0,0 +1,53 @@
+package dchart2
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const pricedata = "Date,Open,High,Low,Close,Volume\n2024-01-02,100,104,97,99,3083\n2024-01-03,99,103,98,101,3911\n"
+
+func TestCandlestick(t *testing.T) {
+	for _, cols := range []string{ohlccols, "Date,Close,Low,High,Open,Volume", "Date,Volume,Open,High,Low,Close"} {
+		c, err := ReadCSV(strings.NewReader(pricedata), cols)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if low, high := c.PriceRange(); low != 97 || high != 104 {
+			t.Errorf("%s: got prices %v to %v, want 97 to 104", cols, low, high)
+		}
+		op, _, _, cl, err := c.ohlc()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if op[0] != 100 || cl[1] != 101 {
+			t.Errorf("%s: got open %v and close %v", cols, op, cl)
+		}
+		r := &recorder{}
+		if err := c.Candlestick(r, 2, false); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.VolumeBar(r, 2); err != nil {
+			t.Fatal(err)
+		}
+		if n := r.count("rect"); n != 2 {
+			t.Errorf("%s: got %d bodies, want 2", cols, n)
+		}
+		r.checkfinite(t)
+	}
+}
+
+func TestCandlestickSeries(t *testing.T) {
+	c, err := ReadCSV(strings.NewReader(pricedata), "Date,Open,High,Low")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var aerr *ArgumentError
+	if err := c.Candlestick(&recorder{}, 2, false); !errors.As(err, &aerr) {
+		t.Errorf("three series: got %v, want an ArgumentError", err)
+	}
+	if low, high := c.PriceRange(); low != c.Minvalue || high != c.Maxvalue {
+		t.Errorf("three series: got prices %v to %v", low, high)
+	}
+}
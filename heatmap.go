@@ -0,0 +1,263 @@
+package dchart2
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ReadCSVMatrix reads a matrix of CSV values into a ChartBox, for heatmaps: a datum for each row,
+// and a series for each column. If csvcols is empty, the data is wide: the first row
+// is the header of the columns, and each following row has a label and a value for each column.
+// Otherwise csvcols names the row, column and value columns of long-format data,
+// for example "Day,Hour,Visits", with rows and columns in order of appearance.
+// Values that are not numbers, and missing values, are NaN, and malformed rows are skipped.
+func ReadCSVMatrix(r io.Reader, csvcols string) (ChartBox, error) {
+	return readcsvmatrix(r, csvcols, false)
+}
+
+// readcsvmatrix reads a matrix of CSV values, strictly or not
+func readcsvmatrix(r io.Reader, csvcols string, strict bool) (ChartBox, error) {
+	input := csv.NewReader(r)
+	input.FieldsPerRecord = -1
+	var (
+		title   string
+		header  []string
+		labels  []string
+		columns []string
+		cells   = map[[2]int]float64{}
+		rows    = map[string]int{}
+		cols    = map[string]int{}
+		ci      []int // the row, column and value columns of long-format data
+	)
+	long := len(csvcols) > 0
+	for {
+		fields, err := input.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if strict {
+				return ChartBox{}, csvparseerror(err)
+			}
+			continue
+		}
+		if len(fields) > 1 && fields[0] == "#" {
+			title = fields[1]
+			continue
+		}
+		if header == nil {
+			header = fields
+			if long {
+				for _, name := range strings.Split(csvcols, ",") {
+					i := -1
+					for k, h := range header {
+						if h == name {
+							i = k
+						}
+					}
+					if i < 0 {
+						return ChartBox{}, &ArgumentError{Name: name, Reason: "no such column"}
+					}
+					ci = append(ci, i)
+				}
+				if len(ci) != 3 {
+					return ChartBox{}, &ArgumentError{Name: csvcols, Reason: "use row,column,value"}
+				}
+			} else {
+				columns = header[1:]
+				for k, name := range columns {
+					cols[name] = k
+				}
+			}
+			continue
+		}
+		value := func(k int) (float64, error) {
+			if k >= len(fields) {
+				return math.NaN(), nil
+			}
+			v, verr := strconv.ParseFloat(strings.TrimSpace(fields[k]), 64)
+			if verr != nil {
+				if strict {
+					line, _ := input.FieldPos(k)
+					return 0, &ParseError{Line: line, Err: verr}
+				}
+				return math.NaN(), nil
+			}
+			return v, nil
+		}
+		if long {
+			if ci[0] >= len(fields) || ci[1] >= len(fields) {
+				continue
+			}
+			row, ok := rows[fields[ci[0]]]
+			if !ok {
+				row = len(labels)
+				rows[fields[ci[0]]] = row
+				labels = append(labels, fields[ci[0]])
+			}
+			col, ok := cols[fields[ci[1]]]
+			if !ok {
+				col = len(columns)
+				cols[fields[ci[1]]] = col
+				columns = append(columns, fields[ci[1]])
+			}
+			v, verr := value(ci[2])
+			if verr != nil {
+				return ChartBox{}, verr
+			}
+			cells[[2]int{row, col}] = v
+			continue
+		}
+		row := len(labels)
+		labels = append(labels, fields[0])
+		for k := range columns {
+			v, verr := value(k + 1)
+			if verr != nil {
+				return ChartBox{}, verr
+			}
+			cells[[2]int{row, k}] = v
+		}
+	}
+
+	data := make([]NameValue, len(labels))
+	series := make([]Series, len(columns))
+	for k, name := range columns {
+		series[k] = Series{Name: xmlesc(name), Values: make([]float64, len(labels))}
+	}
+	minval, maxval := largest, smallest
+	for i, label := range labels {
+		data[i] = NameValue{Label: xmlesc(label), X: labelx(label, float64(i))}
+		for k := range columns {
+			v, ok := cells[[2]int{i, k}]
+			if !ok {
+				v = math.NaN()
+			}
+			series[k].Values[i] = v
+			if v < minval {
+				minval = v
+			}
+			if v > maxval {
+				maxval = v
+			}
+		}
+		if len(columns) > 0 {
+			data[i].Value = series[0].Values[i]
+		}
+	}
+	xminval, xmaxval := xminmax(data)
+	return ChartBox{
+		Title:      xmlesc(title),
+		Data:       data,
+		Series:     series,
+		Minvalue:   minval,
+		Maxvalue:   maxval,
+		XMinvalue:  xminval,
+		XMaxvalue:  xmaxval,
+		TextSize:   1.2,
+		DataFormat: "%.1f",
+		DataColor:  "rgb(128,128,128)",
+		LabelColor: lighttheme.Label,
+		ValueColor: lighttheme.Value,
+		Opacity:    100,
+		Left:       10,
+		Right:      90,
+		Top:        90,
+		Bottom:     50,
+		Zerobased:  true,
+	}, nil
+}
+
+// heatscale returns the color scale of a heatmap: the chart's ColorScale,
+// or if none, a viridis gradient from Minvalue to Maxvalue
+func (c *ChartBox) heatscale() ColorScale {
+	if c.ColorScale != nil {
+		return c.ColorScale
+	}
+	return GradientScale(spreadstops(palettes["viridis"], c.Minvalue, c.Maxvalue))
+}
+
+// cellcolor returns the color of text on a color: black on light colors, white on dark ones
+func cellcolor(color string) string {
+	rgb := parsecolor(color, 1)
+	if (0.299*float64(rgb.R))+(0.587*float64(rgb.G))+(0.114*float64(rgb.B)) > 140 {
+		return "black"
+	}
+	return "white"
+}
+
+// Heatmap makes a heatmap of a matrix: a grid of cells, a row for each label, from the top,
+// and a column for each series (or one column of the data values), colored by the chart's
+// ColorScale, or if none, a viridis gradient from Minvalue to Maxvalue.
+// Rows are labeled on the left, and columns below, as many as fit.
+// With showvalues, the values are shown in the cells. Cells that are NaN are empty.
+// Use ColorBar with the ColorScale for a legend (charts made with the heatmap
+// settings have one at the right, unless ColorBar is "none").
+func (c *ChartBox) Heatmap(deck Renderer, showvalues bool) error {
+	if err := c.need("heatmap", 1); err != nil {
+		return err
+	}
+	scale := c.heatscale()
+	series := c.series()
+	textsize := c.TextSize
+	nrows, ncols := len(c.Data), len(series)
+	w := (c.Right - c.Left) / float64(ncols)
+	h := (c.Top - c.Bottom) / float64(nrows)
+	inset := math.Min(w, h) * 0.04
+	for i, d := range c.Data {
+		y := c.Top - (h * (float64(i) + 0.5))
+		deck.TextEnd(c.Left-textsize, y-(textsize/3), d.Label, "sans", textsize, c.LabelColor)
+		for k, s := range series {
+			v := s.Values[i]
+			if math.IsNaN(v) {
+				continue
+			}
+			x := c.Left + (w * (float64(k) + 0.5))
+			color, ok := scale.Color(v)
+			if !ok {
+				color = c.DataColor
+			}
+			startitem(deck, k, s.Name, d.Label, fmt.Sprintf(c.DataFormat, v), d.Note)
+			deck.Rect(x, y, w-inset, h-inset, color, c.Opacity)
+			enditem(deck)
+			if showvalues {
+				vs := math.Min(textsize*0.75, h/2)
+				deck.TextMid(x, y-(vs/3), fmt.Sprintf(c.DataFormat, v), "mono", vs, cellcolor(color))
+			}
+		}
+	}
+	// column labels, as many as fit
+	longest := 1
+	for _, s := range series {
+		if len(s.Name) > longest {
+			longest = len(s.Name)
+		}
+	}
+	step := 1
+	if fit := int((c.Right - c.Left) / (float64(longest+1) * textsize * 0.6)); fit > 0 && ncols > fit {
+		step = (ncols + fit - 1) / fit
+	}
+	for k := 0; k < ncols; k += step {
+		x := c.Left + (w * (float64(k) + 0.5))
+		deck.TextMid(x, c.Bottom-(textsize*1.5), series[k].Name, "sans", textsize, c.LabelColor)
+	}
+	return nil
+}
+
+// heatmap makes a heatmap, titled, with the default color scale for a color bar
+func (s *Settings) heatmap(deck Renderer, c *ChartBox) error {
+	f := s.Flags
+	c.ColorScale = c.heatscale()
+	if err := c.Heatmap(deck, f.ShowValues); err != nil {
+		return err
+	}
+	if f.ShowTitle {
+		title := *c
+		title.DataColor = c.theme().Title
+		title.CTitle(deck, 5)
+	}
+	return nil
+}
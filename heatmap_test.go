@@ -0,0 +1,46 @@
+package dchart2
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+const matrixdata = "Day,Morning,Evening\nMon,1,5\nTue,3,\nWed,-2,8\n"
+
+func TestHeatmap(t *testing.T) {
+	c, err := ReadCSVMatrix(strings.NewReader(matrixdata), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsNaN(c.Series[1].Values[1]) {
+		t.Errorf("a missing value is %v, want NaN", c.Series[1].Values[1])
+	}
+	r := &recorder{}
+	if err := c.Heatmap(r, true); err != nil {
+		t.Fatal(err)
+	}
+	if n := r.count("rect"); n != 5 {
+		t.Errorf("got %d cells, want 5", n)
+	}
+	r.checkfinite(t)
+}
+
+func TestHeatmapColorBar(t *testing.T) {
+	for _, test := range []struct {
+		colorbar string
+		bar      bool
+	}{{"", true}, {"bottom", true}, {"none", false}} {
+		s := NewChart("heatmap", 0, 0, 0, 0)
+		s.Flags.ReadCSV = true
+		s.Attributes.ColorBar = test.colorbar
+		r := &recorder{}
+		if err := s.GenerateChart(r, strings.NewReader(matrixdata)); err != nil {
+			t.Fatal(err)
+		}
+		if bar := r.count("rect") > 5; bar != test.bar {
+			t.Errorf("colorbar %q: got a color bar %v, want %v", test.colorbar, bar, test.bar)
+		}
+		r.checkfinite(t)
+	}
+}
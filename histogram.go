@@ -0,0 +1,438 @@
+package dchart2
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxbins is the largest number of histogram bins
+const maxbins = 10000
+
+// ReadValues reads a list of numbers into a ChartBox, for histograms:
+// the value of each line is the line itself, or the second field of
+// tab separated label/value lines. A comment line (#) is the title.
+// Values that are not numbers are skipped.
+func ReadValues(r io.Reader) (ChartBox, error) {
+	return readvalues(r, false)
+}
+
+// readvalues reads a list of numbers, strictly or not
+func readvalues(r io.Reader, strict bool) (ChartBox, error) {
+	var values []float64
+	title := ""
+	line := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line++
+		t := strings.TrimSpace(scanner.Text())
+		if len(t) == 0 || strings.HasPrefix(t, "#\t") {
+			continue
+		}
+		if t[0] == '#' {
+			title = strings.TrimSpace(t[1:])
+			continue
+		}
+		fields := strings.Split(t, "\t")
+		field := fields[0]
+		if len(fields) > 1 {
+			field = fields[1]
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			if strict {
+				return ChartBox{}, &ParseError{Line: line, Err: err}
+			}
+			continue
+		}
+		values = append(values, v)
+	}
+	return valuechart(title, values), scanner.Err()
+}
+
+// ReadCSVValues reads a column of CSV numbers into a ChartBox, for histograms.
+// The first row is the column header, and column names the column to read;
+// if empty, the first column is read, and the first row is a header only if it is not a number.
+// Values that are not numbers, and malformed rows, are skipped.
+func ReadCSVValues(r io.Reader, column string) (ChartBox, error) {
+	return readcsvvalues(r, column, false)
+}
+
+// readcsvvalues reads a column of CSV numbers, strictly or not
+func readcsvvalues(r io.Reader, column string, strict bool) (ChartBox, error) {
+	var values []float64
+	input := csv.NewReader(r)
+	input.FieldsPerRecord = -1
+	title := column
+	col := 0
+	for n := 1; ; n++ {
+		fields, err := input.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if strict {
+				return ChartBox{}, csvparseerror(err)
+			}
+			continue
+		}
+		if len(fields) > 1 && fields[0] == "#" {
+			title = fields[1]
+			continue
+		}
+		if n == 1 && len(column) > 0 {
+			col = -1
+			for i, name := range fields {
+				if name == column {
+					col = i
+				}
+			}
+			if col < 0 {
+				return ChartBox{}, &ArgumentError{Name: column, Reason: "no such column"}
+			}
+			continue
+		}
+		if col >= len(fields) {
+			continue
+		}
+		v, verr := strconv.ParseFloat(strings.TrimSpace(fields[col]), 64)
+		if verr != nil {
+			if n == 1 { // a header
+				title = fields[col]
+				continue
+			}
+			if strict {
+				line, _ := input.FieldPos(col)
+				return ChartBox{}, &ParseError{Line: line, Err: verr}
+			}
+			continue
+		}
+		values = append(values, v)
+	}
+	return valuechart(title, values), nil
+}
+
+// valuechart makes a chart of a list of values
+func valuechart(title string, values []float64) ChartBox {
+	data := make([]NameValue, len(values))
+	minval, maxval := largest, smallest
+	for i, v := range values {
+		data[i] = NameValue{Value: v, X: float64(i)}
+		minval = math.Min(minval, v)
+		maxval = math.Max(maxval, v)
+	}
+	return ChartBox{
+		Title:      xmlesc(title),
+		Data:       data,
+		Minvalue:   minval,
+		Maxvalue:   maxval,
+		XMinvalue:  0,
+		XMaxvalue:  float64(len(data) - 1),
+		TextSize:   1.2,
+		DataFormat: "%.1f",
+		DataColor:  "rgb(128,128,128)",
+		LabelColor: lighttheme.Label,
+		ValueColor: lighttheme.Value,
+		Opacity:    100,
+		Left:       10,
+		Right:      90,
+		Top:        90,
+		Bottom:     50,
+		Zerobased:  true,
+	}
+}
+
+// BinEdges returns the edges of histogram bins of the data values, by the rule:
+//
+//	sturges               log2(n)+1 bins (the default)
+//	fd                    bins of width 2 IQR/cbrt(n) (Freedman-Diaconis)
+//	width:w               bins of width w
+//	edges:e1,e2,...       the specified edges, in increasing order (edges: may be omitted)
+//
+// The bins of the sturges, fd and width rules begin and end at multiples of the width,
+// which for sturges and fd is rounded to 1, 2 or 5 times a power of ten.
+// Rules that give more than 10000 bins are errors.
+func (c *ChartBox) BinEdges(rule string) ([]float64, error) {
+	if err := c.need("histogram", 1); err != nil {
+		return nil, err
+	}
+	values := c.datavalues()
+	sort.Float64s(values)
+	min, max := values[0], values[len(values)-1]
+	n := float64(len(values))
+	rule = strings.ToLower(strings.TrimSpace(rule))
+	var edges []float64
+	switch {
+	case rule == "" || rule == "sturges":
+		edges = niceedges(min, max, (max-min)/(math.Ceil(math.Log2(n))+1))
+	case rule == "fd" || rule == "freedman-diaconis":
+		iqr := quantile(values, 0.75) - quantile(values, 0.25)
+		if iqr == 0 {
+			edges = niceedges(min, max, (max-min)/(math.Ceil(math.Log2(n))+1))
+		} else {
+			edges = niceedges(min, max, 2*iqr/math.Cbrt(n))
+		}
+	case strings.HasPrefix(rule, "width:"):
+		w, err := strconv.ParseFloat(strings.TrimPrefix(rule, "width:"), 64)
+		if err != nil || w <= 0 {
+			return nil, &ArgumentError{Name: rule, Reason: "bad bin width"}
+		}
+		edges = widthedges(min, max, w)
+	default:
+		for _, s := range strings.Split(strings.TrimPrefix(rule, "edges:"), ",") {
+			e, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return nil, &ArgumentError{Name: rule, Reason: "unknown binning rule (use sturges, fd, width:w or edges:e1,e2,...)"}
+			}
+			edges = append(edges, e)
+		}
+		if len(edges) < 2 || !sort.Float64sAreSorted(edges) {
+			return nil, &ArgumentError{Name: rule, Reason: "bin edges need at least two values, in increasing order"}
+		}
+	}
+	if edges == nil || len(edges)-1 > maxbins {
+		return nil, &ArgumentError{Name: rule, Reason: fmt.Sprintf("more than %d bins", maxbins)}
+	}
+	return edges, nil
+}
+
+// niceedges returns the edges of bins from min to max, of about the specified width,
+// rounded to 1, 2 or 5 times a power of ten, or nil if there would be more than maxbins bins
+func niceedges(min, max, width float64) []float64 {
+	if width <= 0 || math.IsNaN(width) {
+		return []float64{min - 0.5, max + 0.5}
+	}
+	step := math.Pow10(int(math.Floor(math.Log10(width))))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if step*m >= width {
+			step *= m
+			break
+		}
+	}
+	return widthedges(min, max, step)
+}
+
+// widthedges returns the edges of bins of the width, at multiples of the width, from min to max,
+// or nil if there would be more than maxbins bins
+func widthedges(min, max, width float64) []float64 {
+	start := math.Floor(min/width) * width
+	bins := math.Ceil((max - start) / width)
+	if !(bins <= maxbins) { // also NaN
+		return nil
+	}
+	n := int(bins)
+	if n < 1 {
+		n = 1
+	}
+	edges := make([]float64, n+1)
+	for i := range edges {
+		edges[i] = start + float64(i)*width
+	}
+	return edges
+}
+
+// quantile returns the q quantile of sorted values, interpolating between them
+func quantile(sorted []float64, q float64) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	p := q * float64(n-1)
+	i := int(math.Floor(p))
+	if i >= n-1 {
+		return sorted[n-1]
+	}
+	return sorted[i] + (sorted[i+1]-sorted[i])*(p-float64(i))
+}
+
+// datavalues returns a copy of the data values
+func (c *ChartBox) datavalues() []float64 {
+	values := make([]float64, len(c.Data))
+	for i, d := range c.Data {
+		values[i] = d.Value
+	}
+	return values
+}
+
+// bins returns the counts of the data values in the bins with the edges:
+// each bin includes its lower edge, and the last its upper edge.
+// Values outside the edges are not counted.
+func (c *ChartBox) bins(edges []float64) []float64 {
+	counts := make([]float64, len(edges)-1)
+	last := edges[len(edges)-1]
+	for _, d := range c.Data {
+		v := d.Value
+		if v < edges[0] || v > last {
+			continue
+		}
+		i := sort.SearchFloat64s(edges, v) // the first edge >= v
+		if i == len(edges) || edges[i] > v {
+			i--
+		}
+		if i == len(counts) {
+			i--
+		}
+		counts[i]++
+	}
+	return counts
+}
+
+// density returns the points of the density curve of the data values (a Gaussian kernel
+// density estimate, with Silverman's bandwidth) between the edges, scaled to the counts
+// of bins of the average width
+func (c *ChartBox) density(edges []float64, n int) ([]float64, []float64) {
+	values := c.datavalues()
+	sort.Float64s(values)
+	count := float64(len(values))
+	if count < 2 {
+		return nil, nil
+	}
+	m := mean(values)
+	sd := 0.0
+	for _, v := range values {
+		sd += (v - m) * (v - m)
+	}
+	sd = math.Sqrt(sd / (count - 1))
+	spread := sd
+	if iqr := (quantile(values, 0.75) - quantile(values, 0.25)) / 1.34; iqr > 0 && iqr < spread {
+		spread = iqr
+	}
+	h := 0.9 * spread * math.Pow(count, -0.2)
+	if h <= 0 {
+		return nil, nil
+	}
+	lo, hi := edges[0], edges[len(edges)-1]
+	scale := count * (hi - lo) / float64(len(edges)-1) // counts of the average bin
+	x := make([]float64, n)
+	y := make([]float64, n)
+	for i := range x {
+		x[i] = lo + (hi-lo)*float64(i)/float64(n-1)
+		sum := 0.0
+		for _, v := range values {
+			u := (x[i] - v) / h
+			sum += math.Exp(-u * u / 2)
+		}
+		y[i] = scale * sum / (count * h * math.Sqrt(2*math.Pi))
+	}
+	return x, y
+}
+
+// HistogramMax returns the largest count of the data values in the bins with the edges,
+// or if larger, the peak of the density curve
+func (c *ChartBox) HistogramMax(edges []float64, density bool) float64 {
+	max := 0.0
+	if len(edges) < 2 {
+		return max
+	}
+	for _, n := range c.bins(edges) {
+		max = math.Max(max, n)
+	}
+	if density {
+		_, y := c.density(edges, 100)
+		for _, v := range y {
+			max = math.Max(max, v)
+		}
+	}
+	return max
+}
+
+// Histogram makes a histogram of the data values, counted in the bins with the edges
+// (see BinEdges), with contiguous bars from zero to Maxvalue (see HistogramMax),
+// labeled at the edges. With density, a density curve in the value color is drawn over the bars.
+func (c *ChartBox) Histogram(deck Renderer, edges []float64, density bool) error {
+	if err := c.need("histogram", 1); err != nil {
+		return err
+	}
+	if len(edges) < 2 {
+		return &ArgumentError{Name: "edges", Reason: "histograms need at least two bin edges"}
+	}
+	lo, hi := edges[0], edges[len(edges)-1]
+	counts := c.bins(edges)
+	bins := *c
+	bins.Series = nil
+	bins.Data = make([]NameValue, len(counts))
+	bins.XValue, bins.XTime = true, false
+	bins.XMinvalue, bins.XMaxvalue = lo, hi
+	bins.Minvalue, bins.Zerobased = 0, true
+	format := c.DataFormat
+	for i, n := range counts {
+		bins.Data[i] = NameValue{
+			Label: fmt.Sprintf(format+" to "+format, edges[i], edges[i+1]),
+			Value: n,
+			X:     (edges[i] + edges[i+1]) / 2,
+		}
+	}
+	// each bar is as wide as its bin
+	for i := range bins.Data {
+		bin := bins
+		bin.Data = bins.Data[i : i+1]
+		w := MapRange(edges[i+1], lo, hi, c.Left, c.Right) - MapRange(edges[i], lo, hi, c.Left, c.Right)
+		if err := bin.Bar(deck, w); err != nil {
+			return err
+		}
+	}
+	if density {
+		x, y := c.density(edges, 100)
+		for i := 1; i < len(x); i++ {
+			x1, x2 := MapRange(x[i-1], lo, hi, c.Left, c.Right), MapRange(x[i], lo, hi, c.Left, c.Right)
+			deck.Line(x1, bins.ypos(y[i-1]), x2, bins.ypos(y[i]), c.TextSize/6, c.ValueColor, c.Opacity)
+		}
+	}
+	// edge labels, as many as fit
+	textsize := c.TextSize
+	longest := 1
+	for _, e := range edges {
+		if n := len(fmt.Sprintf(format, e)); n > longest {
+			longest = n
+		}
+	}
+	fit := int((c.Right - c.Left) / (float64(longest+2) * textsize * 0.6))
+	step := 1
+	if fit > 0 && len(edges) > fit {
+		step = (len(edges) + fit - 1) / fit
+	}
+	for i := 0; i < len(edges); i += step {
+		x := MapRange(edges[i], lo, hi, c.Left, c.Right)
+		deck.TextMid(x, c.Bottom-(textsize*2), fmt.Sprintf(format, edges[i]), "sans", textsize, c.LabelColor, c.Opacity)
+	}
+	return nil
+}
+
+// histogram makes a histogram of the data values, binned by the rule of the settings,
+// with a y axis of the counts
+func (s *Settings) histogram(deck Renderer, c *ChartBox) error {
+	f := s.Flags
+	a := s.Attributes
+	edges, err := c.BinEdges(a.Bins)
+	if err != nil {
+		return err
+	}
+	c.Minvalue, c.Maxvalue, c.Zerobased = 0, c.HistogramMax(edges, f.ShowDensity), true
+	if s.UserMax != -1 {
+		c.Maxvalue = s.UserMax
+	}
+	var ymin, ymax, ystep float64
+	if a.YAxisR == "" {
+		ymin, ymax, ystep = cyrange(0, c.Maxvalue, 5)
+		c.Maxvalue = math.Max(c.Maxvalue, ymax)
+	} else {
+		ymin, ymax, ystep = yrange(a.YAxisR)
+	}
+	if err := c.Histogram(deck, edges, f.ShowDensity); err != nil {
+		return err
+	}
+	if f.ShowTitle {
+		title := *c
+		title.DataColor = c.theme().Title
+		title.CTitle(deck, 5)
+	}
+	if f.ShowAxis {
+		c.YAxis(deck, ymin, ymax, ystep, f.ShowGrid)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package dchart2
+
+import (
+	"fmt"
+	"io"
+)
+
+// HTML renders charts as SVG in an HTML page, with the label, value and note
+// of each data value shown as a tooltip on hover. Clicking the legend entry
+// of a series hides or shows the series. The script and styles are embedded in the page.
+// Each slide is a separate SVG element in the page.
+type HTML struct {
+	*SVG
+}
+
+// NewHTML makes an HTML renderer with the specified page size (default 792x612)
+func NewHTML(w io.Writer, width, height float64) *HTML {
+	return &HTML{SVG: NewSVG(w, width, height)}
+}
+
+const htmlstyle = `<style>
+body { font-family: Helvetica, Arial, sans-serif; }
+svg { display: block; margin: 1em auto; max-width: 100%; height: auto; }
+.item:hover { opacity: 0.7; }
+.hidden { display: none; }
+.key { cursor: pointer; }
+.key.off { opacity: 0.3; }
+#dchart-tip { position: fixed; display: none; pointer-events: none; white-space: pre; font-size: 12px;
+  padding: 4px 8px; background: rgba(255,255,255,0.95); border: 1px solid #999; border-radius: 3px; }
+</style>
+`
+
+const htmlscript = `<script>
+(function() {
+  var tip = document.getElementById("dchart-tip");
+  document.querySelectorAll(".item").forEach(function(e) {
+    e.addEventListener("mousemove", function(ev) {
+      var d = e.dataset, lines = [];
+      if (d.series && d.series !== d.label) { lines.push(d.series); }
+      lines.push(d.value ? d.label + ": " + d.value : d.label);
+      if (d.note) { lines.push(d.note); }
+      tip.textContent = lines.join("\n");
+      tip.style.left = (ev.clientX + 12) + "px";
+      tip.style.top = (ev.clientY + 12) + "px";
+      tip.style.display = "block";
+    });
+    e.addEventListener("mouseleave", function() { tip.style.display = "none"; });
+  });
+  document.querySelectorAll(".key").forEach(function(k) {
+    k.addEventListener("click", function() {
+      var off = k.classList.toggle("off");
+      k.closest("svg").querySelectorAll(".item").forEach(function(e) {
+        if (e.dataset.index === k.dataset.index) { e.classList.toggle("hidden", off); }
+      });
+    });
+  });
+})();
+</script>
+`
+
+// StartDeck begins the HTML page
+func (p *HTML) StartDeck() {
+	fmt.Fprintf(p.dest, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n%s</head>\n<body>\n", htmlstyle)
+}
+
+// EndDeck ends the HTML page, adding the tooltip and script
+func (p *HTML) EndDeck() {
+	fmt.Fprintf(p.dest, "<div id=\"dchart-tip\"></div>\n%s</body>\n</html>\n", htmlscript)
+}
+
+// StartSlide begins an SVG element, with an optional background color
+func (p *HTML) StartSlide(colors ...string) {
+	p.start(colors...)
+}
+
+// StartItem begins the shapes of a data value
+func (p *HTML) StartItem(index int, series, label, value, note string) {
+	fmt.Fprintf(p.dest, "<g class=\"item\" data-index=\"%d\" data-series=\"%s\" data-label=\"%s\" data-value=\"%s\" data-note=\"%s\">\n",
+		index, attresc(series), attresc(label), attresc(value), attresc(note))
+}
+
+// StartKey begins the shapes of a legend entry; entries that are not of a series
+// are not keys, and do nothing when clicked
+func (p *HTML) StartKey(index int, series string) {
+	if index < 0 {
+		fmt.Fprintf(p.dest, "<g data-series=\"%s\">\n", attresc(series))
+		return
+	}
+	fmt.Fprintf(p.dest, "<g class=\"key\" data-index=\"%d\" data-series=\"%s\">\n", index, attresc(series))
+}
+
+// EndItem ends a data value or legend entry
+func (p *HTML) EndItem() {
+	fmt.Fprintln(p.dest, "</g>")
+}
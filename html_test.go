@@ -0,0 +1,46 @@
+package dchart2
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHTMLSeriesKeys(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewHTML(&buf, 0, 0)
+	c := readchart(t, "# Quarters\t\t\nQ1\t10\t5\nQ2\t20\t7\n")
+	p.StartSlide()
+	if err := c.Bar(p, 2); err != nil {
+		t.Fatal(err)
+	}
+	c.Legend(p, "right", "", "")
+	p.EndSlide()
+	s := buf.String()
+	// unnamed series are keyed by their index
+	for _, want := range []string{`class="key" data-index="0"`, `class="key" data-index="1"`, `class="item" data-index="1"`} {
+		if !strings.Contains(s, want) {
+			t.Errorf("no %s in\n%s", want, s)
+		}
+	}
+}
+
+func TestHTMLLabelKeys(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewHTML(&buf, 0, 0)
+	c := readchart(t, "a\t10\tred\nb\t20\tblue\n")
+	p.StartSlide()
+	if err := c.Donut(p, 40, 5, true, false); err != nil {
+		t.Fatal(err)
+	}
+	c.Legend(p, "right", "", "")
+	p.EndSlide()
+	// the labels match no series, so they are not keys
+	s := buf.String()
+	if !strings.Contains(s, `<g data-series="a">`) {
+		t.Errorf("no legend entry for a in\n%s", s)
+	}
+	if strings.Contains(s, `class="key"`) {
+		t.Errorf("a label legend has keys:\n%s", s)
+	}
+}
@@ -0,0 +1,116 @@
+package dchart2
+
+import (
+	"fmt"
+	"math"
+)
+
+// Layout arranges several charts on a slide: in a grid, in rows (one chart
+// above another) or in columns (side by side), separated by gutters, within the
+// bounds of the layout. A grid has the specified number of columns,
+// or about as many columns as rows if Columns is zero.
+// Each chart may have a title above it, and the layout an overall title.
+type Layout struct {
+	Arrange   string
+	Columns   int
+	Gutter    float64
+	Left      float64
+	Right     float64
+	Top       float64
+	Bottom    float64
+	Title     string
+	Titles    []string
+	TitleSize float64
+}
+
+// Cell is the bounds of a chart in a layout
+type Cell struct {
+	Left, Right, Top, Bottom float64
+}
+
+// NewLayout makes a layout for the named arrangement (grid, rows or columns),
+// with the default bounds (10, 90, 85, 15) and title size (1.5)
+func NewLayout(arrange string, columns int, gutter float64) Layout {
+	return Layout{Arrange: arrange, Columns: columns, Gutter: gutter, Left: 10, Right: 90, Top: 85, Bottom: 15, TitleSize: 1.5}
+}
+
+// grid returns the number of rows and columns for n charts
+func (l Layout) grid(n int) (int, int) {
+	switch l.Arrange {
+	case "rows", "row":
+		return n, 1
+	case "columns", "cols", "col", "column":
+		return 1, n
+	}
+	cols := l.Columns
+	if cols <= 0 {
+		cols = int(math.Ceil(math.Sqrt(float64(n))))
+	}
+	if cols > n {
+		cols = n
+	}
+	return (n + cols - 1) / cols, cols
+}
+
+// Cells returns the bounds of n charts, in order from the top left,
+// leaving room for the titles. It is an error if the cells have no width or height
+// after the gutters and titles.
+func (l Layout) Cells(n int) ([]Cell, error) {
+	if n < 1 {
+		return nil, nil
+	}
+	rows, cols := l.grid(n)
+	top := l.Top
+	if len(l.Title) > 0 { // room for the titles of the charts under the overall title
+		top -= l.TitleSize*2 + l.Gutter
+	}
+	titlespace := 0.0
+	for _, t := range l.Titles {
+		if len(t) > 0 {
+			titlespace = l.TitleSize * 2
+			break
+		}
+	}
+	w := (l.Right - l.Left - l.Gutter*float64(cols-1)) / float64(cols)
+	h := (top - l.Bottom - l.Gutter*float64(rows-1)) / float64(rows)
+	if w <= 0 || h-titlespace <= 0 {
+		return nil, &ArgumentError{Name: "layout", Reason: fmt.Sprintf("no room for %d charts (%.2fx%.2f cells)", n, w, h-titlespace)}
+	}
+	cells := make([]Cell, n)
+	for i := range cells {
+		r, c := i/cols, i%cols
+		x := l.Left + float64(c)*(w+l.Gutter)
+		y := top - float64(r)*(h+l.Gutter)
+		cells[i] = Cell{Left: x, Right: x + w, Top: y - titlespace, Bottom: y - h}
+	}
+	return cells, nil
+}
+
+// DrawTitles draws the overall title and the titles of the charts in the cells
+func (l Layout) DrawTitles(deck Renderer, cells []Cell, color string) {
+	if len(l.Title) > 0 {
+		deck.TextMid(l.Left+(l.Right-l.Left)/2, l.Top, l.Title, "sans", l.TitleSize*2, color)
+	}
+	for i, cell := range cells {
+		if i < len(l.Titles) && len(l.Titles[i]) > 0 {
+			deck.TextMid(cell.Left+(cell.Right-cell.Left)/2, cell.Top+l.TitleSize, l.Titles[i], "sans", l.TitleSize, color)
+		}
+	}
+}
+
+// Place sets the bounds of the charts to the cells of the layout, and draws the titles
+func (l Layout) Place(deck Renderer, charts []*ChartBox) error {
+	cells, err := l.Cells(len(charts))
+	if err != nil {
+		return err
+	}
+	for i, c := range charts {
+		c.Left, c.Right, c.Top, c.Bottom = cells[i].Left, cells[i].Right, cells[i].Top, cells[i].Bottom
+	}
+	color := lighttheme.Label
+	if len(charts) > 0 {
+		color = charts[0].LabelColor
+	}
+	l.DrawTitles(deck, cells, color)
+	return nil
+}
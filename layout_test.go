@@ -0,0 +1,38 @@
+package dchart2
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCells(t *testing.T) {
+	l := NewLayout("grid", 0, 5)
+	cells, err := l.Cells(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cells) != 4 {
+		t.Fatalf("got %d cells, want 4", len(cells))
+	}
+	for i, c := range cells {
+		if c.Right <= c.Left || c.Top <= c.Bottom {
+			t.Errorf("cell %d is empty: %+v", i, c)
+		}
+	}
+	if cells[0].Left != cells[2].Left || cells[0].Top <= cells[2].Top {
+		t.Errorf("cell 2 is not below cell 0: %+v %+v", cells[0], cells[2])
+	}
+}
+
+func TestCellsTooSmall(t *testing.T) {
+	var aerr *ArgumentError
+	for _, l := range []Layout{
+		NewLayout("columns", 0, 40),
+		NewLayout("rows", 0, 40),
+		{Arrange: "rows", Left: 10, Right: 90, Top: 50, Bottom: 45, TitleSize: 3, Titles: []string{"a", "b"}},
+	} {
+		if _, err := l.Cells(3); !errors.As(err, &aerr) {
+			t.Errorf("%+v: got %v, want an ArgumentError", l, err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package dchart2
+
+import "math"
+
+// LegendEntry is an entry in a legend: a color swatch and label
+type LegendEntry struct {
+	Label string
+	Color string
+}
+
+// Legend makes a legend of the entries, or if there are none, of the chart:
+// its series, or with one series, its data labels in their (Note or palette) colors,
+// or else the title in the data color.
+// placement is one of "right" (default), "bottom", or the inside corners
+// "top-left", "top-right", "bottom-left" and "bottom-right".
+// flow is "horizontal" or "vertical" (the default, except at the bottom),
+// and swatch is "block" (default), "line" or "dot".
+func (c *ChartBox) Legend(deck Renderer, placement, flow, swatch string, entries ...LegendEntry) {
+	keyed := false // entries of the series of the chart
+	if len(entries) == 0 {
+		entries = c.legendentries()
+		keyed = len(c.Series) > 1
+	}
+	if len(entries) == 0 {
+		return
+	}
+	textsize := c.TextSize
+	step := textsize * 1.8
+	horizontal := flow == "horizontal" || (flow == "" && placement == "bottom")
+
+	// size of the legend
+	var w, h float64
+	for _, e := range entries {
+		ew := legendwidth(e.Label, textsize)
+		if horizontal {
+			w += ew
+		} else {
+			w = math.Max(w, ew)
+		}
+	}
+	if horizontal {
+		h = textsize
+	} else {
+		h = float64(len(entries)-1) * step
+	}
+
+	x, y := c.Right+textsize*2, c.Top-textsize/2
+	switch placement {
+	case "bottom":
+		x, y = c.Left, c.Bottom-textsize*5
+	case "top-left", "tl":
+		x, y = c.Left+textsize, c.Top-textsize
+	case "top-right", "tr":
+		x, y = c.Right-w, c.Top-textsize
+	case "bottom-left", "bl":
+		x, y = c.Left+textsize, c.Bottom+textsize+h
+	case "bottom-right", "br":
+		x, y = c.Right-w, c.Bottom+textsize+h
+	}
+	c.drawlegend(deck, x, y, entries, horizontal, swatch, keyed)
+}
+
+// drawlegend draws the legend entries, beginning at (x, y).
+// If keyed, the entries are those of the series of the chart, in order.
+func (c *ChartBox) drawlegend(deck Renderer, x, y float64, entries []LegendEntry, horizontal bool, swatch string, keyed bool) {
+	textsize := c.TextSize
+	for k, e := range entries {
+		if !keyed {
+			k = -1
+		}
+		startkey(deck, k, e.Label)
+		switch swatch {
+		case "line":
+			deck.Line(x, y, x+textsize, y, textsize/4, e.Color)
+		case "dot":
+			deck.Circle(x+(textsize/2), y, textsize, e.Color)
+		default:
+			deck.Rect(x+(textsize/2), y, textsize, textsize, e.Color)
+		}
+		deck.Text(x+(textsize*1.5), y-(textsize/3), e.Label, "sans", textsize, c.LabelColor)
+		enditem(deck)
+		if horizontal {
+			x += legendwidth(e.Label, textsize)
+		} else {
+			y -= textsize * 1.8
+		}
+	}
+}
+
+// legendentries returns the legend entries of a chart: the series, the data labels
+// if they have colors, or the title
+func (c *ChartBox) legendentries() []LegendEntry {
+	if len(c.Series) > 1 {
+		series := c.series()
+		entries := make([]LegendEntry, len(series))
+		for k, s := range series {
+			color, _ := c.stdcolor(k, s.Color, c.Opacity, true)
+			entries[k] = LegendEntry{Label: s.Name, Color: color}
+		}
+		return entries
+	}
+	colored := len(c.palette(c.DataColor)) > 0
+	for _, d := range c.Data {
+		if iscolor(d.Note) {
+			colored = true
+		}
+	}
+	if !colored {
+		return []LegendEntry{{Label: c.Title, Color: c.DataColor}}
+	}
+	entries := make([]LegendEntry, len(c.Data))
+	for i, d := range c.Data {
+		note := d.Note
+		if !iscolor(note) {
+			note = ""
+		}
+		color, _ := c.stdcolor(i, note, c.Opacity, true)
+		entries[i] = LegendEntry{Label: d.Label, Color: color}
+	}
+	return entries
+}
+
+// legendwidth returns the approximate width of a horizontal legend entry
+func legendwidth(label string, textsize float64) float64 {
+	return (textsize * 3) + (float64(len(label)) * textsize * 0.6)
+}
@@ -0,0 +1,159 @@
+package dchart2
+
+import (
+	"io"
+	"math"
+)
+
+// SmallMultiples draws a panel for each chart, in the cells of the layout, titled
+// with the chart titles. Each panel is drawn by the draw function.
+// The panels share the Y scale, from the smallest minimum to the largest maximum,
+// unless freey is true. The Y axis is labeled only on the panels of the first column
+// (every panel with free scales), and the x axis only on the bottom panel of each column.
+// The first error drawing a panel is returned.
+func SmallMultiples(deck Renderer, charts []ChartBox, layout Layout, freey bool, draw func(deck Renderer, c *ChartBox) error) error {
+	n := len(charts)
+	if n == 0 {
+		return &DataError{Chart: "small multiples", Need: 1, Have: 0}
+	}
+	_, cols := layout.grid(n)
+	min, max := largest, smallest
+	for _, c := range charts {
+		min = math.Min(min, c.Minvalue)
+		max = math.Max(max, c.Maxvalue)
+	}
+	layout.Titles = make([]string, n)
+	for i := range charts {
+		layout.Titles[i] = charts[i].Title
+	}
+	cells, err := layout.Cells(n)
+	if err != nil {
+		return err
+	}
+	layout.DrawTitles(deck, cells, charts[0].LabelColor)
+	for i := range charts {
+		c := &charts[i]
+		c.Left, c.Right, c.Top, c.Bottom = cells[i].Left, cells[i].Right, cells[i].Top, cells[i].Bottom
+		if !freey {
+			c.Minvalue, c.Maxvalue = min, max
+		}
+		ymin, ymax, ystep := c.axisrange(4)
+		c.Maxvalue = math.Max(c.Maxvalue, ymax)
+		if err := draw(deck, c); err != nil {
+			return err
+		}
+		c.ZeroLine(deck, 0.1)
+		if freey || i%cols == 0 {
+			c.YAxis(deck, ymin, ymax, ystep, false)
+		}
+		if i+cols >= n { // nothing below
+			c.XLabel(deck, c.labelinterval())
+		}
+	}
+	return nil
+}
+
+// axisrange returns the range and step of about n Y axis labels
+func (c *ChartBox) axisrange(n int) (float64, float64, float64) {
+	ymin := zerobase(c.Zerobased, c.Minvalue)
+	if ymin < 0 {
+		return xtickrange(ymin, c.Maxvalue, n*2)
+	}
+	return cyrange(ymin, c.Maxvalue, n)
+}
+
+// labelinterval returns the interval of x axis labels that fit across the chart
+func (c *ChartBox) labelinterval() int {
+	longest := 1
+	for _, d := range c.Data {
+		if len(d.Label) > longest {
+			longest = len(d.Label)
+		}
+	}
+	fit := int((c.Right - c.Left) / (float64(longest+2) * c.TextSize * 0.6))
+	if fit < 1 || len(c.Data) <= fit {
+		return 1
+	}
+	return (len(c.Data) + fit - 1) / fit
+}
+
+// multiples makes small multiples from long-format CSV data, grouped by the facet column,
+// in a grid within the chart bounds. The panels are bar charts, with line,
+// scatter and volume (area) charts as specified.
+func (s *Settings) multiples(deck Renderer, r io.Reader) error {
+	f := s.Flags
+	m := s.Measures
+	a := s.Attributes
+	charts, err := readcsvgroups(r, a.FacetColumn, a.CSVCols, f.Strict)
+	if err != nil {
+		return err
+	}
+	colorscale, err := NewColorScale(a.DataCondition)
+	if err != nil {
+		return err
+	}
+	var yscale Scale
+	if len(a.YScale) > 0 {
+		if yscale, err = NewScale(a.YScale); err != nil {
+			return err
+		}
+	}
+	theme, err := a.theme()
+	if err != nil {
+		return err
+	}
+	layout := NewLayout("grid", 0, 0)
+	if m.Left > 0 {
+		layout.Left = m.Left
+	}
+	if m.Right > 0 {
+		layout.Right = m.Right
+	}
+	if m.Top > 0 {
+		layout.Top = m.Top
+	}
+	if m.Bottom > 0 {
+		layout.Bottom = m.Bottom
+	}
+	for i := range charts {
+		c := &charts[i]
+		c.YScale = yscale
+		c.ColorScale = colorscale
+		c.SetTheme(theme)
+		s.datarange(c)
+		if len(a.DataFmt) > 0 {
+			c.DataFormat = a.DataFmt
+		}
+		if m.TextSize > 0 {
+			c.TextSize = m.TextSize * 0.75
+		}
+	}
+	layout.TitleSize = charts[0].TextSize * 1.25
+	layout.Gutter = charts[0].TextSize * 8
+	if f.FreeScale { // room for the y axis labels
+		layout.Gutter *= 1.25
+	}
+	return SmallMultiples(deck, charts, layout, f.FreeScale, func(deck Renderer, c *ChartBox) error {
+		var err error
+		if f.ShowBar {
+			size := m.BarWidth
+			if size == 0 {
+				size = (c.Right - c.Left) / float64(len(c.Data)+1)
+			}
+			err = c.ConditionalBar(deck, size, smallest, largest, "")
+		}
+		if f.ShowVolume && err == nil {
+			op := c.Opacity
+			c.Opacity = m.VolumeOpacity
+			err = c.Area(deck)
+			c.Opacity = op
+		}
+		if f.ShowLine && err == nil {
+			err = c.ConditionalLine(deck, m.LineWidth, smallest, largest, "")
+		}
+		if f.ShowScatter && err == nil {
+			err = c.ConditionalScatter(deck, m.LineWidth, smallest, largest, "")
+		}
+		return err
+	})
+}
@@ -0,0 +1,335 @@
+package dchart2
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// PDF renders charts as a vector PDF document, using the standard PDF fonts
+// (Helvetica, Times and Courier for sans, serif and mono). Each slide is a page.
+// The percentage coordinates are scaled to the page size: x and sizes to the width, y to the height.
+type PDF struct {
+	Width   float64
+	Height  float64
+	dest    *countwriter
+	offsets map[int]int64
+	nobj    int
+	pages   []int
+	alphas  map[int]bool
+	content bytes.Buffer
+}
+
+// countwriter counts the bytes written, for the cross-reference table
+type countwriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countwriter) Write(b []byte) (int, error) {
+	n, err := cw.w.Write(b)
+	cw.n += int64(n)
+	return n, err
+}
+
+// pdffont describes a standard font: its resource name, PDF name, and character widths
+type pdffont struct {
+	id     string
+	name   string
+	widths []int // for characters 32-126, in 1/1000 of the font size
+}
+
+// fixed object numbers
+const (
+	pdfcatalog   = 1
+	pdfpages     = 2
+	pdfresources = 3
+	pdffirstfont = 4
+)
+
+// pdffontorder is the order of the font objects
+var pdffontorder = []string{"sans", "serif", "mono"}
+
+var pdffonts = map[string]pdffont{
+	"sans": {"F1", "Helvetica", []int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584}},
+	"serif": {"F2", "Times-Roman", []int{
+		250, 333, 408, 500, 500, 833, 778, 180, 333, 333, 500, 564, 250, 333, 250, 278,
+		500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 278, 278, 564, 564, 564, 444,
+		921, 722, 667, 667, 722, 611, 556, 722, 722, 333, 389, 722, 611, 889, 722, 722,
+		556, 722, 667, 556, 611, 722, 722, 944, 722, 722, 611, 333, 278, 333, 469, 500,
+		333, 444, 500, 444, 500, 444, 333, 500, 500, 278, 278, 500, 278, 778, 500, 500,
+		500, 500, 333, 389, 278, 500, 500, 722, 500, 500, 444, 480, 200, 480, 541}},
+	"mono": {"F3", "Courier", nil},
+}
+
+// NewPDF makes a PDF renderer with the specified page size in points (default 792x612)
+func NewPDF(w io.Writer, width, height float64) *PDF {
+	if width <= 0 {
+		width = 792
+	}
+	if height <= 0 {
+		height = 612
+	}
+	return &PDF{Width: width, Height: height, dest: &countwriter{w: w},
+		offsets: map[int]int64{}, alphas: map[int]bool{}, nobj: pdffirstfont + len(pdffontorder) - 1}
+}
+
+// StartDeck begins the document, writing the fonts
+func (p *PDF) StartDeck() {
+	fmt.Fprintf(p.dest, "%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+	for i, name := range pdffontorder {
+		p.object(pdffirstfont+i, fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", pdffonts[name].name))
+	}
+}
+
+// EndDeck ends the document, writing the page tree, resources and cross-reference table
+func (p *PDF) EndDeck() {
+	kids := make([]string, len(p.pages))
+	for i, n := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", n)
+	}
+	p.object(pdfpages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(p.pages)))
+
+	alphas := make([]int, 0, len(p.alphas))
+	for a := range p.alphas {
+		alphas = append(alphas, a)
+	}
+	sort.Ints(alphas)
+	states := make([]string, len(alphas))
+	for i, a := range alphas {
+		n := p.newobject()
+		p.object(n, fmt.Sprintf("<< /Type /ExtGState /ca %.2f /CA %.2f >>", float64(a)/100, float64(a)/100))
+		states[i] = fmt.Sprintf("/GS%d %d 0 R", a, n)
+	}
+	fonts := make([]string, len(pdffontorder))
+	for i, name := range pdffontorder {
+		fonts[i] = fmt.Sprintf("/%s %d 0 R", pdffonts[name].id, pdffirstfont+i)
+	}
+	p.object(pdfresources, fmt.Sprintf("<< /Font << %s >> /ExtGState << %s >> >>", strings.Join(fonts, " "), strings.Join(states, " ")))
+	p.object(pdfcatalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pdfpages))
+
+	xref := p.dest.n
+	fmt.Fprintf(p.dest, "xref\n0 %d\n0000000000 65535 f \n", p.nobj+1)
+	for i := 1; i <= p.nobj; i++ {
+		fmt.Fprintf(p.dest, "%010d 00000 n \n", p.offsets[i])
+	}
+	fmt.Fprintf(p.dest, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", p.nobj+1, pdfcatalog, xref)
+}
+
+// StartSlide begins a page, with an optional background color
+func (p *PDF) StartSlide(colors ...string) {
+	p.content.Reset()
+	if len(colors) > 0 && len(colors[0]) > 0 {
+		fmt.Fprintf(&p.content, "%s 0 0 %.2f %.2f re f\n", pdfcolor(colors[0], "rg"), p.Width, p.Height)
+	}
+}
+
+// EndSlide ends the page, writing its content
+func (p *PDF) EndSlide() {
+	stream := p.newobject()
+	p.object(stream, fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.content.Len(), p.content.String()))
+	page := p.newobject()
+	p.object(page, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources %d 0 R /Contents %d 0 R >>",
+		pdfpages, p.Width, p.Height, pdfresources, stream))
+	p.pages = append(p.pages, page)
+}
+
+// Line makes a line
+func (p *PDF) Line(x1, y1, x2, y2, size float64, color string, opacity ...float64) {
+	fmt.Fprintf(&p.content, "q %s%s %.2f w %.2f %.2f m %.2f %.2f l S Q\n",
+		p.alpha(opacity), pdfcolor(shapecolor(color), "RG"), p.w(size), p.x(x1), p.y(y1), p.x(x2), p.y(y2))
+}
+
+// Circle makes a circle, with the specified diameter
+func (p *PDF) Circle(x, y, w float64, color string, opacity ...float64) {
+	fmt.Fprintf(&p.content, "q %s%s %s h f Q\n",
+		p.alpha(opacity), pdfcolor(shapecolor(color), "rg"), arcpath(p.x(x), p.y(y), p.w(w)/2, 0, 360))
+}
+
+// Rect makes a rectangle centered at (x,y)
+func (p *PDF) Rect(x, y, w, h float64, color string, opacity ...float64) {
+	fmt.Fprintf(&p.content, "q %s%s %.2f %.2f %.2f %.2f re f Q\n",
+		p.alpha(opacity), pdfcolor(shapecolor(color), "rg"), p.x(x)-p.w(w)/2, p.y(y)-p.h(h)/2, p.w(w), p.h(h))
+}
+
+// Polygon makes a filled polygon
+func (p *PDF) Polygon(x, y []float64, color string, opacity ...float64) {
+	if len(x) == 0 || len(y) == 0 {
+		return
+	}
+	var path strings.Builder
+	for i := 0; i < len(x) && i < len(y); i++ {
+		op := "l"
+		if i == 0 {
+			op = "m"
+		}
+		fmt.Fprintf(&path, "%.2f %.2f %s ", p.x(x[i]), p.y(y[i]), op)
+	}
+	fmt.Fprintf(&p.content, "q %s%s %sh f Q\n", p.alpha(opacity), pdfcolor(shapecolor(color), "rg"), path.String())
+}
+
+// Arc makes an arc of the specified width (diameter) and size (stroke width),
+// from angle a1 to a2 (degrees, counterclockwise from 3 o'clock)
+func (p *PDF) Arc(x, y, w, h, size, a1, a2 float64, color string, opacity ...float64) {
+	fmt.Fprintf(&p.content, "q %s%s %.2f w %s S Q\n",
+		p.alpha(opacity), pdfcolor(shapecolor(color), "RG"), p.w(size), arcpath(p.x(x), p.y(y), p.w(w)/2, a1, a2))
+}
+
+// Text places left-aligned text
+func (p *PDF) Text(x, y float64, s, font string, size float64, color string, opacity ...float64) {
+	p.text(x, y, s, "start", font, 0, size, color, opacity)
+}
+
+// TextMid places centered text
+func (p *PDF) TextMid(x, y float64, s, font string, size float64, color string, opacity ...float64) {
+	p.text(x, y, s, "middle", font, 0, size, color, opacity)
+}
+
+// TextEnd places right-aligned text
+func (p *PDF) TextEnd(x, y float64, s, font string, size float64, color string, opacity ...float64) {
+	p.text(x, y, s, "end", font, 0, size, color, opacity)
+}
+
+// TextRotate places text rotated counterclockwise by the specified degrees
+func (p *PDF) TextRotate(x, y float64, s, align, font string, rotation, size float64, color string, opacity ...float64) {
+	p.text(x, y, s, svganchor(align), font, rotation, size, color, opacity)
+}
+
+// text places text with the specified anchor and rotation
+func (p *PDF) text(x, y float64, s, anchor, font string, rotation, size float64, color string, opacity []float64) {
+	f, ok := pdffonts[font]
+	if !ok {
+		f = pdffonts["sans"]
+	}
+	s = xmlunesc(s)
+	fs := p.w(size)
+	var dx float64
+	switch anchor {
+	case "middle":
+		dx = f.width(s, fs) / 2
+	case "end":
+		dx = f.width(s, fs)
+	}
+	t := rotation * math.Pi / 180
+	sin, cos := math.Sin(t), math.Cos(t)
+	fmt.Fprintf(&p.content, "q %s%s BT /%s %.2f Tf %.4f %.4f %.4f %.4f %.2f %.2f Tm (%s) Tj ET Q\n",
+		p.alpha(opacity), pdfcolor(textcolor(color), "rg"), f.id, fs,
+		cos, sin, -sin, cos, p.x(x)-dx*cos, p.y(y)-dx*sin, pdfstring(s))
+}
+
+// width returns the width of a string at the font size
+func (f pdffont) width(s string, size float64) float64 {
+	w := 0
+	for _, r := range s {
+		switch {
+		case f.widths == nil:
+			w += 600
+		case r >= 32 && r <= 126:
+			w += f.widths[r-32]
+		default:
+			w += f.widths['0'-32]
+		}
+	}
+	return float64(w) * size / 1000
+}
+
+// alpha returns the operator setting the opacity, if less than opaque
+func (p *PDF) alpha(opacity []float64) string {
+	a := int(math.Round(opacityfrac(opacity) * 100))
+	if a >= 100 {
+		return ""
+	}
+	if a < 0 {
+		a = 0
+	}
+	p.alphas[a] = true
+	return fmt.Sprintf("/GS%d gs ", a)
+}
+
+// newobject allocates an object number
+func (p *PDF) newobject() int {
+	p.nobj++
+	return p.nobj
+}
+
+// object writes an object, recording its offset
+func (p *PDF) object(n int, body string) {
+	p.offsets[n] = p.dest.n
+	fmt.Fprintf(p.dest, "%d 0 obj\n%s\nendobj\n", n, body)
+}
+
+// pdfcolor returns the operator setting the fill (rg) or stroke (RG) color
+func pdfcolor(color, op string) string {
+	c := parsecolor(color, 1)
+	return fmt.Sprintf("%.3f %.3f %.3f %s", float64(c.R)/255, float64(c.G)/255, float64(c.B)/255, op)
+}
+
+// pdfstring encodes text as a PDF string, in WinAnsi (Latin-1) encoding
+func pdfstring(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 32 && r <= 126:
+			b.WriteRune(r)
+		case r >= 160 && r <= 255:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// arcpath returns the path of a circular arc from angle a1 to a2 (degrees, counterclockwise),
+// as Bézier curves of at most 90 degrees each
+func arcpath(cx, cy, r, a1, a2 float64) string {
+	var b strings.Builder
+	n := int(math.Ceil(math.Abs(a2-a1) / 90))
+	if n < 1 {
+		n = 1
+	}
+	step := (a2 - a1) / float64(n) * math.Pi / 180
+	k := 4.0 / 3.0 * math.Tan(step/4)
+	t := a1 * math.Pi / 180
+	fmt.Fprintf(&b, "%.2f %.2f m", cx+r*math.Cos(t), cy+r*math.Sin(t))
+	for i := 0; i < n; i++ {
+		t2 := t + step
+		c1x, c1y := cx+r*(math.Cos(t)-k*math.Sin(t)), cy+r*(math.Sin(t)+k*math.Cos(t))
+		c2x, c2y := cx+r*(math.Cos(t2)+k*math.Sin(t2)), cy+r*(math.Sin(t2)-k*math.Cos(t2))
+		fmt.Fprintf(&b, " %.2f %.2f %.2f %.2f %.2f %.2f c", c1x, c1y, c2x, c2y, cx+r*math.Cos(t2), cy+r*math.Sin(t2))
+		t = t2
+	}
+	return b.String()
+}
+
+// x converts a percentage to a horizontal coordinate
+func (p *PDF) x(v float64) float64 {
+	return (v / 100) * p.Width
+}
+
+// y converts a percentage to a vertical coordinate
+func (p *PDF) y(v float64) float64 {
+	return (v / 100) * p.Height
+}
+
+// w converts a percentage to a size relative to the width
+func (p *PDF) w(v float64) float64 {
+	return (v / 100) * p.Width
+}
+
+// h converts a percentage to a size relative to the height
+func (p *PDF) h(v float64) float64 {
+	return (v / 100) * p.Height
+}
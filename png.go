@@ -0,0 +1,308 @@
+package dchart2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// PNG renders charts as antialiased PNG images, using the embedded Go fonts.
+// The percentage coordinates are scaled to the image size: x and sizes to the width,
+// y to the height. Each slide is a separate image, so write one slide per PNG.
+type PNG struct {
+	Width  int
+	Height int
+	DPI    float64
+	dest   io.Writer
+	img    *image.RGBA
+	fonts  map[string]*opentype.Font
+	faces  map[string]font.Face
+	err    error
+}
+
+// pngfonts maps deck font names to the embedded fonts; there is no serif Go font.
+var pngfonts = map[string][]byte{
+	"sans":  goregular.TTF,
+	"serif": goregular.TTF,
+	"mono":  gomono.TTF,
+	"bold":  gobold.TTF,
+}
+
+// NewPNG makes a PNG renderer with the specified size in pixels (default 1024x768)
+// and resolution (default 96 DPI)
+func NewPNG(w io.Writer, width, height int, dpi float64) *PNG {
+	if width <= 0 {
+		width = 1024
+	}
+	if height <= 0 {
+		height = 768
+	}
+	if dpi <= 0 {
+		dpi = 96
+	}
+	return &PNG{Width: width, Height: height, DPI: dpi, dest: w,
+		fonts: map[string]*opentype.Font{}, faces: map[string]font.Face{}}
+}
+
+// StartDeck begins the deck
+func (p *PNG) StartDeck() {}
+
+// EndDeck ends the deck
+func (p *PNG) EndDeck() {
+	for _, face := range p.faces {
+		face.Close()
+	}
+}
+
+// StartSlide begins an image, with an optional background color (default white)
+func (p *PNG) StartSlide(colors ...string) {
+	p.img = image.NewRGBA(image.Rect(0, 0, p.Width, p.Height))
+	bg := color.RGBA{255, 255, 255, 255}
+	if len(colors) > 0 && len(colors[0]) > 0 {
+		bg = parsecolor(colors[0], 1)
+	}
+	draw.Draw(p.img, p.img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+}
+
+// EndSlide writes the image as PNG, recording the resolution
+func (p *PNG) EndSlide() {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, p.img)
+	if err == nil {
+		_, err = p.dest.Write(pngdpi(buf.Bytes(), p.DPI))
+	}
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// Err returns the first error encoding or writing an image
+func (p *PNG) Err() error {
+	return p.err
+}
+
+// Line makes a line
+func (p *PNG) Line(x1, y1, x2, y2, size float64, color string, opacity ...float64) {
+	ax, ay, bx, by := p.x(x1), p.y(y1), p.x(x2), p.y(y2)
+	l := math.Hypot(bx-ax, by-ay)
+	if l == 0 || !finite(l) || !finite(size) {
+		return
+	}
+	w := math.Max(p.w(size), 1) / 2
+	nx, ny := -(by-ay)/l*w, (bx-ax)/l*w
+	p.fill([]float64{ax + nx, bx + nx, bx - nx, ax - nx}, []float64{ay + ny, by + ny, by - ny, ay - ny}, shapecolor(color), opacity)
+}
+
+// Circle makes a circle, with the specified diameter
+func (p *PNG) Circle(x, y, w float64, color string, opacity ...float64) {
+	px, py := ring(p.x(x), p.y(y), p.w(w)/2, 0, 360)
+	p.fill(px, py, shapecolor(color), opacity)
+}
+
+// Rect makes a rectangle centered at (x,y)
+func (p *PNG) Rect(x, y, w, h float64, color string, opacity ...float64) {
+	cx, cy, hw, hh := p.x(x), p.y(y), p.w(w)/2, p.h(h)/2
+	p.fill([]float64{cx - hw, cx + hw, cx + hw, cx - hw}, []float64{cy - hh, cy - hh, cy + hh, cy + hh}, shapecolor(color), opacity)
+}
+
+// Polygon makes a filled polygon
+func (p *PNG) Polygon(x, y []float64, color string, opacity ...float64) {
+	n := len(x)
+	if len(y) < n {
+		n = len(y)
+	}
+	px := make([]float64, n)
+	py := make([]float64, n)
+	for i := 0; i < n; i++ {
+		px[i], py[i] = p.x(x[i]), p.y(y[i])
+	}
+	p.fill(px, py, shapecolor(color), opacity)
+}
+
+// Arc makes an arc of the specified width (diameter) and size (stroke width),
+// from angle a1 to a2 (degrees, counterclockwise from 3 o'clock)
+func (p *PNG) Arc(x, y, w, h, size, a1, a2 float64, color string, opacity ...float64) {
+	if !finite(a1) || !finite(a2) {
+		return
+	}
+	cx, cy, r, hs := p.x(x), p.y(y), p.w(w)/2, p.w(size)/2
+	ox, oy := ring(cx, cy, r+hs, a1, a2)
+	ix, iy := ring(cx, cy, math.Max(r-hs, 0), a2, a1)
+	p.fill(append(ox, ix...), append(oy, iy...), shapecolor(color), opacity)
+}
+
+// Text places left-aligned text
+func (p *PNG) Text(x, y float64, s, font string, size float64, color string, opacity ...float64) {
+	p.text(x, y, s, "start", font, 0, size, color, opacity)
+}
+
+// TextMid places centered text
+func (p *PNG) TextMid(x, y float64, s, font string, size float64, color string, opacity ...float64) {
+	p.text(x, y, s, "middle", font, 0, size, color, opacity)
+}
+
+// TextEnd places right-aligned text
+func (p *PNG) TextEnd(x, y float64, s, font string, size float64, color string, opacity ...float64) {
+	p.text(x, y, s, "end", font, 0, size, color, opacity)
+}
+
+// TextRotate places text rotated counterclockwise by the specified degrees
+func (p *PNG) TextRotate(x, y float64, s, align, font string, rotation, size float64, color string, opacity ...float64) {
+	p.text(x, y, s, svganchor(align), font, rotation, size, color, opacity)
+}
+
+// text draws text with the specified anchor and rotation
+func (p *PNG) text(x, y float64, s, anchor, fontname string, rotation, size float64, color string, opacity []float64) {
+	s = xmlunesc(s)
+	face := p.face(fontname, p.w(size))
+	if face == nil || len(s) == 0 {
+		return
+	}
+	src := image.NewUniform(parsecolor(textcolor(color), opacityfrac(opacity)))
+	adv := float64(font.MeasureString(face, s)) / 64
+	var dx float64
+	switch anchor {
+	case "middle":
+		dx = adv / 2
+	case "end":
+		dx = adv
+	}
+	tx, ty := p.x(x), p.y(y)
+	if rotation == 0 {
+		d := font.Drawer{Dst: p.img, Src: src, Face: face, Dot: fixed.P(int(tx-dx), int(ty))}
+		d.DrawString(s)
+		return
+	}
+	// draw the text unrotated, then rotate it about the anchor
+	m := face.Metrics()
+	ascent, descent := m.Ascent.Ceil(), m.Descent.Ceil()
+	tmp := image.NewRGBA(image.Rect(0, 0, int(adv)+2, ascent+descent))
+	d := font.Drawer{Dst: tmp, Src: src, Face: face, Dot: fixed.P(0, ascent)}
+	d.DrawString(s)
+	t := rotation * math.Pi / 180
+	sin, cos := math.Sin(t), math.Cos(t)
+	ax, ay := dx, float64(ascent)
+	aff := f64.Aff3{
+		cos, sin, tx - cos*ax - sin*ay,
+		-sin, cos, ty + sin*ax - cos*ay,
+	}
+	draw.BiLinear.Transform(p.img, aff, tmp, tmp.Bounds(), draw.Over, nil)
+}
+
+// face returns the font face for the deck font name at the pixel size
+func (p *PNG) face(name string, px float64) font.Face {
+	if _, ok := pngfonts[name]; !ok {
+		name = "sans"
+	}
+	key := name + strconv.FormatFloat(px, 'f', 1, 64)
+	if face, ok := p.faces[key]; ok {
+		return face
+	}
+	f, ok := p.fonts[name]
+	if !ok {
+		var err error
+		f, err = opentype.Parse(pngfonts[name])
+		if err != nil {
+			return nil
+		}
+		p.fonts[name] = f
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: px * 72 / p.DPI, DPI: p.DPI, Hinting: font.HintingNone})
+	if err != nil {
+		return nil
+	}
+	p.faces[key] = face
+	return face
+}
+
+// fill fills the polygon with the color
+func (p *PNG) fill(x, y []float64, color string, opacity []float64) {
+	if len(x) < 3 {
+		return
+	}
+	for i := range x { // the rasterizer fails on coordinates that are not finite
+		if !finite(x[i]) || !finite(y[i]) {
+			return
+		}
+	}
+	r := vector.NewRasterizer(p.Width, p.Height)
+	r.DrawOp = draw.Over
+	r.MoveTo(float32(x[0]), float32(y[0]))
+	for i := 1; i < len(x); i++ {
+		r.LineTo(float32(x[i]), float32(y[i]))
+	}
+	r.ClosePath()
+	r.Draw(p.img, p.img.Bounds(), image.NewUniform(parsecolor(color, opacityfrac(opacity))), image.Point{})
+}
+
+// ring returns the points of a circular arc from angle a1 to a2 (degrees, counterclockwise)
+func ring(cx, cy, r, a1, a2 float64) ([]float64, []float64) {
+	if !finite(a1) || !finite(a2) {
+		return nil, nil
+	}
+	n := int(math.Min(math.Abs(a2-a1), 360)/5) + 2 // at most a turn, in 5 degree steps
+	x := make([]float64, n)
+	y := make([]float64, n)
+	for i := 0; i < n; i++ {
+		t := (a1 + (a2-a1)*float64(i)/float64(n-1)) * math.Pi / 180
+		x[i] = cx + r*math.Cos(t)
+		y[i] = cy - r*math.Sin(t)
+	}
+	return x, y
+}
+
+// x converts a percentage to a horizontal coordinate
+func (p *PNG) x(v float64) float64 {
+	return (v / 100) * float64(p.Width)
+}
+
+// y converts a percentage to a vertical coordinate, with the origin at the top
+func (p *PNG) y(v float64) float64 {
+	return float64(p.Height) - (v/100)*float64(p.Height)
+}
+
+// w converts a percentage to a size relative to the width
+func (p *PNG) w(v float64) float64 {
+	return (v / 100) * float64(p.Width)
+}
+
+// h converts a percentage to a size relative to the height
+func (p *PNG) h(v float64) float64 {
+	return (v / 100) * float64(p.Height)
+}
+
+// pngdpi adds the resolution (pHYs chunk) after the header of encoded PNG data
+func pngdpi(data []byte, dpi float64) []byte {
+	const ihdrend = 8 + 25 // signature, then the IHDR chunk
+	if len(data) < ihdrend {
+		return data
+	}
+	ppm := uint32(dpi / 0.0254) // pixels per meter
+	chunk := make([]byte, 21)
+	binary.BigEndian.PutUint32(chunk[0:], 9)
+	copy(chunk[4:], "pHYs")
+	binary.BigEndian.PutUint32(chunk[8:], ppm)
+	binary.BigEndian.PutUint32(chunk[12:], ppm)
+	chunk[16] = 1 // unit is the meter
+	binary.BigEndian.PutUint32(chunk[17:], crc32.ChecksumIEEE(chunk[4:17]))
+	out := make([]byte, 0, len(data)+len(chunk))
+	out = append(out, data[:ihdrend]...)
+	out = append(out, chunk...)
+	return append(out, data[ihdrend:]...)
+}
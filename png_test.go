@@ -0,0 +1,51 @@
+package dchart2
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestPNGOnePoint(t *testing.T) {
+	c := readchart(t, "a\t5\n")
+	var buf bytes.Buffer
+	p := NewPNG(&buf, 200, 150, 96)
+	p.StartSlide()
+	if err := c.Scatter(p, 1); err != nil {
+		t.Fatal(err)
+	}
+	p.EndSlide()
+	if buf.Len() == 0 {
+		t.Error("no image")
+	}
+}
+
+func TestPNGNotFinite(t *testing.T) {
+	p := NewPNG(&bytes.Buffer{}, 200, 150, 96)
+	p.StartSlide()
+	nan, inf := math.NaN(), math.Inf(1)
+	p.Line(nan, 10, 20, 20, 1, "red")
+	p.Line(10, 10, inf, 20, 1, "red")
+	p.Circle(nan, nan, 5, "red")
+	p.Rect(50, nan, 10, 10, "red")
+	p.Polygon([]float64{10, nan, 30}, []float64{10, 20, 10}, "red")
+	p.Arc(50, 50, 20, 20, 2, 0, nan, "red")
+	p.EndSlide()
+}
+
+// failwriter fails every write
+type failwriter struct{}
+
+func (failwriter) Write(b []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestPNGWriteError(t *testing.T) {
+	p := NewPNG(failwriter{}, 20, 20, 96)
+	p.StartSlide()
+	p.EndSlide()
+	if p.Err() == nil {
+		t.Error("a failed write is not an error")
+	}
+}
@@ -0,0 +1,102 @@
+package dchart2
+
+import "github.com/ajstarks/deck/generate"
+
+// Renderer draws the primitives used to make charts.
+// Coordinates and sizes are percentages of the canvas, with the origin at the lower left;
+// angles are in degrees and opacity is a percentage (0-100).
+// The deck markup generator, *generate.Deck, is a Renderer.
+type Renderer interface {
+	Line(x1, y1, x2, y2, size float64, color string, opacity ...float64)
+	Circle(x, y, w float64, color string, opacity ...float64)
+	Rect(x, y, w, h float64, color string, opacity ...float64)
+	Polygon(x, y []float64, color string, opacity ...float64)
+	Arc(x, y, w, h, size, a1, a2 float64, color string, opacity ...float64)
+	Text(x, y float64, s, font string, size float64, color string, opacity ...float64)
+	TextMid(x, y float64, s, font string, size float64, color string, opacity ...float64)
+	TextEnd(x, y float64, s, font string, size float64, color string, opacity ...float64)
+	TextRotate(x, y float64, s, align, font string, rotation, size float64, color string, opacity ...float64)
+}
+
+// Document is a Renderer that makes a deck of slides
+type Document interface {
+	Renderer
+	StartDeck()
+	EndDeck()
+	StartSlide(colors ...string)
+	EndSlide()
+}
+
+// Annotator is implemented by renderers that attach data to the shapes that draw it,
+// for example as tooltips. Chart methods call StartItem before drawing the shapes
+// of a data value, and EndItem after. Legend entries are bracketed by StartKey and EndItem.
+// The index is that of the series of the value or entry in the chart,
+// or -1 if it is not one of several series (a data label, for example).
+type Annotator interface {
+	StartItem(index int, series, label, value, note string)
+	StartKey(index int, series string)
+	EndItem()
+}
+
+var (
+	_ Document  = (*generate.Deck)(nil)
+	_ Document  = (*SVG)(nil)
+	_ Document  = (*PNG)(nil)
+	_ Document  = (*Terminal)(nil)
+	_ Document  = (*HTML)(nil)
+	_ Document  = (*PDF)(nil)
+	_ Annotator = (*HTML)(nil)
+)
+
+// startitem begins the shapes of a data value, if the renderer is an Annotator
+func startitem(deck Renderer, index int, series, label, value, note string) {
+	if a, ok := deck.(Annotator); ok {
+		a.StartItem(index, series, label, value, note)
+	}
+}
+
+// startkey begins the shapes of a legend entry, if the renderer is an Annotator
+func startkey(deck Renderer, index int, series string) {
+	if a, ok := deck.(Annotator); ok {
+		a.StartKey(index, series)
+	}
+}
+
+// enditem ends a data value or legend entry, if the renderer is an Annotator
+func enditem(deck Renderer) {
+	if a, ok := deck.(Annotator); ok {
+		a.EndItem()
+	}
+}
+
+// opacityfrac returns the optional opacity percentage as a fraction (default 1)
+func opacityfrac(opacity []float64) float64 {
+	if len(opacity) == 0 {
+		return 1
+	}
+	return opacity[0] / 100
+}
+
+// shapecolor returns the color of shapes, using the default (gray) if empty
+func shapecolor(color string) string {
+	if len(color) == 0 {
+		return "rgb(127,127,127)"
+	}
+	return color
+}
+
+// textcolor returns the color of text, using the default (black) if empty
+func textcolor(color string) string {
+	if len(color) == 0 {
+		return "black"
+	}
+	return color
+}
+
+// fontname maps deck font names (sans, serif, mono) to the names used by a renderer
+func fontname(fonts map[string]string, font string) string {
+	if f, ok := fonts[font]; ok {
+		return f
+	}
+	return fonts["sans"]
+}
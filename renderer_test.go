@@ -0,0 +1,158 @@
+package dchart2
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// op is a recorded drawing primitive: its kind, coordinates, sizes and angles,
+// and its text or color
+type op struct {
+	kind  string
+	args  []float64
+	text  string
+	color string
+}
+
+// recorder is a Renderer and Annotator that records what is drawn
+type recorder struct {
+	ops []op
+}
+
+var (
+	_ Renderer  = (*recorder)(nil)
+	_ Annotator = (*recorder)(nil)
+)
+
+func (r *recorder) add(kind, text, color string, args ...float64) {
+	r.ops = append(r.ops, op{kind: kind, args: args, text: text, color: color})
+}
+
+func (r *recorder) Line(x1, y1, x2, y2, size float64, color string, opacity ...float64) {
+	r.add("line", "", color, x1, y1, x2, y2, size)
+}
+
+func (r *recorder) Circle(x, y, w float64, color string, opacity ...float64) {
+	r.add("circle", "", color, x, y, w)
+}
+
+func (r *recorder) Rect(x, y, w, h float64, color string, opacity ...float64) {
+	r.add("rect", "", color, x, y, w, h)
+}
+
+func (r *recorder) Polygon(x, y []float64, color string, opacity ...float64) {
+	r.add("polygon", "", color, append(append([]float64{}, x...), y...)...)
+}
+
+func (r *recorder) Arc(x, y, w, h, size, a1, a2 float64, color string, opacity ...float64) {
+	r.add("arc", "", color, x, y, w, h, size, a1, a2)
+}
+
+func (r *recorder) Text(x, y float64, s, font string, size float64, color string, opacity ...float64) {
+	r.add("text", s, color, x, y, size)
+}
+
+func (r *recorder) TextMid(x, y float64, s, font string, size float64, color string, opacity ...float64) {
+	r.add("text", s, color, x, y, size)
+}
+
+func (r *recorder) TextEnd(x, y float64, s, font string, size float64, color string, opacity ...float64) {
+	r.add("text", s, color, x, y, size)
+}
+
+func (r *recorder) TextRotate(x, y float64, s, align, font string, rotation, size float64, color string, opacity ...float64) {
+	r.add("text", s, color, x, y, rotation, size)
+}
+
+func (r *recorder) StartItem(index int, series, label, value, note string) {
+	r.add("item", label, series, float64(index))
+}
+
+func (r *recorder) StartKey(index int, series string) {
+	r.add("key", "", series, float64(index))
+}
+
+func (r *recorder) EndItem() {
+	r.add("end", "", "")
+}
+
+// count returns the number of primitives of a kind
+func (r *recorder) count(kind string) int {
+	n := 0
+	for _, o := range r.ops {
+		if o.kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// texts returns the text drawn
+func (r *recorder) texts() []string {
+	var s []string
+	for _, o := range r.ops {
+		if o.kind == "text" {
+			s = append(s, o.text)
+		}
+	}
+	return s
+}
+
+// checkfinite fails the test if any primitive has a coordinate that is NaN or infinite
+func (r *recorder) checkfinite(t *testing.T) {
+	t.Helper()
+	for _, o := range r.ops {
+		for _, v := range o.args {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				t.Errorf("%s %q has non-finite arguments %v", o.kind, o.text, o.args)
+				break
+			}
+		}
+	}
+}
+
+// readchart reads tab separated values for tests
+func readchart(t *testing.T, data string) ChartBox {
+	t.Helper()
+	c, err := ReadTSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestAnnotations(t *testing.T) {
+	c := readchart(t, "a\t1\nb\t2\n")
+	r := &recorder{}
+	if err := c.Bar(r, 2); err != nil {
+		t.Fatal(err)
+	}
+	if r.count("item") != 2 || r.count("end") != 2 {
+		t.Errorf("got %d items and %d ends, want 2 of each", r.count("item"), r.count("end"))
+	}
+}
+
+func TestSeriesIndex(t *testing.T) {
+	c := readchart(t, multidata)
+	r := &recorder{}
+	if err := c.Bar(r, 2); err != nil {
+		t.Fatal(err)
+	}
+	c.Legend(r, "right", "", "")
+	var items, keys []float64
+	for _, o := range r.ops {
+		switch o.kind {
+		case "item":
+			items = append(items, o.args[0])
+		case "key":
+			keys = append(keys, o.args[0])
+		}
+	}
+	if len(keys) != 2 || keys[0] != 0 || keys[1] != 1 {
+		t.Errorf("got keys %v, want [0 1]", keys)
+	}
+	if len(items) != 6 || items[0] != 0 || items[5] != 1 {
+		t.Errorf("got items of series %v", items)
+	}
+}
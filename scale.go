@@ -0,0 +1,202 @@
+package dchart2
+
+import "math"
+
+// Scale transforms data values before they are mapped onto a chart,
+// and chooses the values of the axis labels
+type Scale interface {
+	// Map transforms a data value
+	Map(v float64) float64
+	// Ticks returns the major (labeled) and minor tick values from min to max
+	Ticks(min, max, step float64) (major, minor []float64)
+}
+
+// LinearScale maps values unchanged
+type LinearScale struct{}
+
+// LogScale maps values to their logarithm in the specified base
+type LogScale struct {
+	Base float64
+}
+
+// SymlogScale maps values to a symmetric logarithm (base 10) that is
+// linear near zero (within the Constant), and keeps the sign of the value
+type SymlogScale struct {
+	Constant float64
+}
+
+// NewScale returns the scale with the specified name:
+// "linear" (or ""), "log10" (or "log"), "log2" or "symlog"
+func NewScale(name string) (Scale, error) {
+	switch name {
+	case "", "linear":
+		return LinearScale{}, nil
+	case "log", "log10":
+		return LogScale{Base: 10}, nil
+	case "log2":
+		return LogScale{Base: 2}, nil
+	case "symlog":
+		return SymlogScale{Constant: 1}, nil
+	}
+	return nil, &ArgumentError{Name: name, Reason: "unknown scale"}
+}
+
+// Map returns the value unchanged
+func (s LinearScale) Map(v float64) float64 {
+	return v
+}
+
+// Ticks returns the values from min to max, every step
+func (s LinearScale) Ticks(min, max, step float64) ([]float64, []float64) {
+	var major []float64
+	if step <= 0 {
+		return major, nil
+	}
+	for v := min; v <= max; v += step {
+		major = append(major, v)
+	}
+	return major, nil
+}
+
+// Map returns the logarithm of the value
+func (s LogScale) Map(v float64) float64 {
+	return math.Log(v) / math.Log(s.Base)
+}
+
+// Ticks returns the powers of the base from min to max,
+// with the multiples of each power as minor ticks
+func (s LogScale) Ticks(min, max, step float64) ([]float64, []float64) {
+	var major, minor []float64
+	if min <= 0 || max <= 0 || s.Base <= 1 {
+		return major, minor
+	}
+	lo := math.Floor(s.Map(min))
+	hi := math.Ceil(s.Map(max))
+	for k := lo; k <= hi; k++ {
+		p := math.Pow(s.Base, k)
+		if within(p, min, max) {
+			major = append(major, p)
+		}
+		for m := 2.0; m < s.Base; m++ {
+			if within(m*p, min, max) {
+				minor = append(minor, m*p)
+			}
+		}
+	}
+	return major, minor
+}
+
+// Map returns the symmetric logarithm of the value
+func (s SymlogScale) Map(v float64) float64 {
+	c := s.Constant
+	if c <= 0 {
+		c = 1
+	}
+	if v < 0 {
+		return -math.Log10(1 - v/c)
+	}
+	return math.Log10(1 + v/c)
+}
+
+// Ticks returns zero and the positive and negative powers of ten from min to max,
+// with the multiples of each power as minor ticks
+func (s SymlogScale) Ticks(min, max, step float64) ([]float64, []float64) {
+	var major, minor []float64
+	if within(0, min, max) {
+		major = append(major, 0)
+	}
+	c := s.Constant
+	if c <= 0 {
+		c = 1
+	}
+	lo := math.Floor(math.Log10(c))
+	hi := math.Ceil(math.Log10(math.Max(math.Abs(min), math.Abs(max))))
+	for k := lo; k <= hi; k++ {
+		p := math.Pow(10, k)
+		for _, sign := range []float64{-1, 1} {
+			if within(sign*p, min, max) {
+				major = append(major, sign*p)
+			}
+			for m := 2.0; m < 10; m++ {
+				if within(sign*m*p, min, max) {
+					minor = append(minor, sign*m*p)
+				}
+			}
+		}
+	}
+	return major, minor
+}
+
+// within tests whether a value is between min and max, allowing for rounding
+func within(v, min, max float64) bool {
+	e := (max - min) * 1e-9
+	return v >= min-e && v <= max+e
+}
+
+// finite tests whether a value is neither infinite nor NaN
+func finite(v float64) bool {
+	return !math.IsInf(v, 0) && !math.IsNaN(v)
+}
+
+// ydomain returns the range of data values mapped onto the chart.
+// If the scale cannot map the base (the logarithm of zero, for example),
+// the data minimum is used instead.
+func (c *ChartBox) ydomain() (float64, float64) {
+	ymin := zerobase(c.Zerobased, c.Minvalue)
+	if c.YScale != nil && !finite(c.YScale.Map(ymin)) {
+		ymin = c.Minvalue
+	}
+	return ymin, c.Maxvalue
+}
+
+// vmap maps a data value to the range (low, high), using the chart's scale
+func (c *ChartBox) vmap(v, low, high float64) float64 {
+	ymin, ymax := c.ydomain()
+	if c.YScale == nil {
+		return MapRange(v, ymin, ymax, low, high)
+	}
+	p := MapRange(c.YScale.Map(v), c.YScale.Map(ymin), c.YScale.Map(ymax), low, high)
+	if !finite(p) {
+		return low
+	}
+	return p
+}
+
+// ybase returns the vertical position of the zero baseline, within the chart
+func (c *ChartBox) ybase() float64 {
+	return c.zeropos(c.Bottom, c.Top)
+}
+
+// xbase returns the horizontal position of the zero baseline of horizontal charts
+func (c *ChartBox) xbase() float64 {
+	return c.zeropos(c.Left, c.Right)
+}
+
+// zeropos returns the position of zero within the range (low, high),
+// or the nearer end if zero is outside the data range
+func (c *ChartBox) zeropos(low, high float64) float64 {
+	ymin, ymax := c.ydomain()
+	switch {
+	case ymin >= 0:
+		return low
+	case ymax <= 0:
+		return high
+	}
+	return c.vmap(0, low, high)
+}
+
+// ypos returns the vertical position of a data value
+func (c *ChartBox) ypos(v float64) float64 {
+	return c.vmap(v, c.Bottom, c.Top)
+}
+
+// yticks returns the major and minor values of the y axis labels
+func (c *ChartBox) yticks(min, max, step float64) ([]float64, []float64) {
+	if c.YScale == nil {
+		return LinearScale{}.Ticks(min, max, step)
+	}
+	if !finite(c.YScale.Map(min)) {
+		min, _ = c.ydomain()
+	}
+	return c.YScale.Ticks(min, max, step)
+}
@@ -0,0 +1,19 @@
+package dchart2
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewScale(t *testing.T) {
+	for _, name := range []string{"", "linear", "log", "log10", "log2", "symlog"} {
+		if _, err := NewScale(name); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+	_, err := NewScale("cubic")
+	var aerr *ArgumentError
+	if !errors.As(err, &aerr) {
+		t.Errorf("an unknown scale is %T, want *ArgumentError", err)
+	}
+}
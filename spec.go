@@ -0,0 +1,123 @@
+package dchart2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ChartSpec specifies the data files of a chart, its title in a layout, and its settings.
+// Flags, Attributes and Measures hold the fields of the corresponding
+// Settings types, keyed by field name (case does not matter), for example:
+//
+//	flags:
+//	  showbar: true
+//	attributes:
+//	  datacolor: steelblue
+//	measures:
+//	  top: 80
+type ChartSpec struct {
+	Files      []string
+	Title      string
+	Flags      json.RawMessage
+	Attributes json.RawMessage
+	Measures   json.RawMessage
+}
+
+// Spec is a chart specification: the settings for all charts (and any files to
+// draw with them), a list of charts, each with its own files and settings,
+// and the layout of the charts, with the fields of Layout
+type Spec struct {
+	ChartSpec
+	Charts []ChartSpec
+	Layout json.RawMessage
+}
+
+// ReadSpec reads a chart specification, in JSON or YAML.
+// Unknown keys are errors.
+func ReadSpec(r io.Reader) (Spec, error) {
+	var spec Spec
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return spec, err
+	}
+	// YAML is a superset of JSON, so read both as YAML, then decode as JSON
+	var doc interface{}
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return spec, err
+	}
+	if doc == nil {
+		return spec, nil
+	}
+	js, err := json.Marshal(doc)
+	if err != nil {
+		return spec, err
+	}
+	if err := decodestrict(js, &spec); err != nil {
+		return spec, specerror("", err)
+	}
+	return spec, nil
+}
+
+// Apply sets the specified fields of the settings, leaving the others unchanged
+func (cs ChartSpec) Apply(s *Settings) error {
+	sections := []struct {
+		name string
+		data json.RawMessage
+		dest interface{}
+	}{
+		{"flags", cs.Flags, &s.Flags},
+		{"attributes", cs.Attributes, &s.Attributes},
+		{"measures", cs.Measures, &s.Measures},
+	}
+	for _, sec := range sections {
+		if len(sec.data) == 0 || string(sec.data) == "null" {
+			continue
+		}
+		if err := decodestrict(sec.data, sec.dest); err != nil {
+			return specerror(sec.name, err)
+		}
+	}
+	return nil
+}
+
+// ApplyLayout sets the fields of the layout specified in the Spec, if any,
+// reporting whether there is a layout. The arrangement defaults to grid.
+func (spec Spec) ApplyLayout(l *Layout) (bool, error) {
+	if len(spec.Layout) == 0 || string(spec.Layout) == "null" {
+		return false, nil
+	}
+	if err := decodestrict(spec.Layout, l); err != nil {
+		return false, specerror("layout", err)
+	}
+	if len(l.Arrange) == 0 {
+		l.Arrange = "grid"
+	}
+	return true, nil
+}
+
+// decodestrict decodes JSON, rejecting unknown fields
+func decodestrict(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// specerror describes an error in a section of a specification
+func specerror(section string, err error) error {
+	msg := err.Error()
+	if field := strings.TrimPrefix(msg, "json: unknown field "); field != msg {
+		msg = "unknown key " + field
+	}
+	if te, ok := err.(*json.UnmarshalTypeError); ok {
+		msg = fmt.Sprintf("%s: cannot use %s as %s", te.Field, te.Value, te.Type)
+	}
+	if len(section) > 0 {
+		return fmt.Errorf("%s: %s", section, msg)
+	}
+	return fmt.Errorf("%s", msg)
+}
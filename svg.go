@@ -0,0 +1,184 @@
+package dchart2
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// SVG renders charts as standalone SVG documents. The percentage coordinates
+// are scaled to the page size: x and sizes to the width, y to the height.
+// Each slide is a separate SVG document, so write one slide per SVG.
+type SVG struct {
+	Width  float64
+	Height float64
+	dest   io.Writer
+}
+
+// svgfonts maps deck font names to SVG font families
+var svgfonts = map[string]string{
+	"sans":  "Helvetica,Arial,sans-serif",
+	"serif": "Times,Times New Roman,serif",
+	"mono":  "Courier,Courier New,monospace",
+}
+
+// NewSVG makes an SVG renderer with the specified page size (default 792x612)
+func NewSVG(w io.Writer, width, height float64) *SVG {
+	if width <= 0 {
+		width = 792
+	}
+	if height <= 0 {
+		height = 612
+	}
+	return &SVG{Width: width, Height: height, dest: w}
+}
+
+// StartDeck begins the deck
+func (p *SVG) StartDeck() {}
+
+// EndDeck ends the deck
+func (p *SVG) EndDeck() {}
+
+// StartSlide begins an SVG document, with an optional background color
+func (p *SVG) StartSlide(colors ...string) {
+	fmt.Fprintf(p.dest, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	p.start(colors...)
+}
+
+// start begins the svg element, with an optional background color
+func (p *SVG) start(colors ...string) {
+	fmt.Fprintf(p.dest, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%.0f\" height=\"%.0f\" viewBox=\"0 0 %.0f %.0f\">\n",
+		p.Width, p.Height, p.Width, p.Height)
+	if len(colors) > 0 && len(colors[0]) > 0 {
+		fmt.Fprintf(p.dest, "<rect x=\"0\" y=\"0\" width=\"%.0f\" height=\"%.0f\" fill=\"%s\"/>\n", p.Width, p.Height, attresc(colors[0]))
+	}
+}
+
+// EndSlide ends the SVG document
+func (p *SVG) EndSlide() {
+	fmt.Fprintln(p.dest, "</svg>")
+}
+
+// Line makes a line
+func (p *SVG) Line(x1, y1, x2, y2, size float64, color string, opacity ...float64) {
+	fmt.Fprintf(p.dest, "<line x1=\"%.2f\" y1=\"%.2f\" x2=\"%.2f\" y2=\"%.2f\" stroke=\"%s\" stroke-width=\"%.2f\" stroke-opacity=\"%.2f\"/>\n",
+		p.x(x1), p.y(y1), p.x(x2), p.y(y2), attresc(shapecolor(color)), p.w(size), opacityfrac(opacity))
+}
+
+// Circle makes a circle, with the specified diameter
+func (p *SVG) Circle(x, y, w float64, color string, opacity ...float64) {
+	fmt.Fprintf(p.dest, "<circle cx=\"%.2f\" cy=\"%.2f\" r=\"%.2f\" fill=\"%s\" fill-opacity=\"%.2f\"/>\n",
+		p.x(x), p.y(y), p.w(w)/2, attresc(shapecolor(color)), opacityfrac(opacity))
+}
+
+// Rect makes a rectangle centered at (x,y)
+func (p *SVG) Rect(x, y, w, h float64, color string, opacity ...float64) {
+	fmt.Fprintf(p.dest, "<rect x=\"%.2f\" y=\"%.2f\" width=\"%.2f\" height=\"%.2f\" fill=\"%s\" fill-opacity=\"%.2f\"/>\n",
+		p.x(x)-p.w(w)/2, p.y(y)-p.h(h)/2, p.w(w), p.h(h), attresc(shapecolor(color)), opacityfrac(opacity))
+}
+
+// Polygon makes a filled polygon
+func (p *SVG) Polygon(x, y []float64, color string, opacity ...float64) {
+	points := make([]string, 0, len(x))
+	for i := 0; i < len(x) && i < len(y); i++ {
+		points = append(points, fmt.Sprintf("%.2f,%.2f", p.x(x[i]), p.y(y[i])))
+	}
+	fmt.Fprintf(p.dest, "<polygon points=\"%s\" fill=\"%s\" fill-opacity=\"%.2f\"/>\n",
+		strings.Join(points, " "), attresc(shapecolor(color)), opacityfrac(opacity))
+}
+
+// Arc makes an arc of the specified width (diameter) and size (stroke width),
+// from angle a1 to a2 (degrees, counterclockwise from 3 o'clock)
+func (p *SVG) Arc(x, y, w, h, size, a1, a2 float64, color string, opacity ...float64) {
+	if a2-a1 >= 360 { // a complete circle is two half circles
+		p.Arc(x, y, w, h, size, a1, a1+180, color, opacity...)
+		p.Arc(x, y, w, h, size, a1+180, a2, color, opacity...)
+		return
+	}
+	cx, cy, r := p.x(x), p.y(y), p.w(w)/2
+	t1 := a1 * math.Pi / 180
+	t2 := a2 * math.Pi / 180
+	large := 0
+	if a2-a1 > 180 {
+		large = 1
+	}
+	fmt.Fprintf(p.dest, "<path d=\"M%.2f,%.2f A%.2f,%.2f 0 %d,0 %.2f,%.2f\" fill=\"none\" stroke=\"%s\" stroke-width=\"%.2f\" stroke-opacity=\"%.2f\"/>\n",
+		cx+r*math.Cos(t1), cy-r*math.Sin(t1), r, r, large, cx+r*math.Cos(t2), cy-r*math.Sin(t2),
+		attresc(shapecolor(color)), p.w(size), opacityfrac(opacity))
+}
+
+// Text places left-aligned text
+func (p *SVG) Text(x, y float64, s, font string, size float64, color string, opacity ...float64) {
+	p.text(x, y, s, "start", font, 0, size, color, opacity)
+}
+
+// TextMid places centered text
+func (p *SVG) TextMid(x, y float64, s, font string, size float64, color string, opacity ...float64) {
+	p.text(x, y, s, "middle", font, 0, size, color, opacity)
+}
+
+// TextEnd places right-aligned text
+func (p *SVG) TextEnd(x, y float64, s, font string, size float64, color string, opacity ...float64) {
+	p.text(x, y, s, "end", font, 0, size, color, opacity)
+}
+
+// TextRotate places text rotated counterclockwise by the specified degrees
+func (p *SVG) TextRotate(x, y float64, s, align, font string, rotation, size float64, color string, opacity ...float64) {
+	p.text(x, y, s, svganchor(align), font, rotation, size, color, opacity)
+}
+
+// text places text with the specified anchor and rotation
+func (p *SVG) text(x, y float64, s, anchor, font string, rotation, size float64, color string, opacity []float64) {
+	tx, ty := p.x(x), p.y(y)
+	transform := ""
+	if rotation != 0 {
+		transform = fmt.Sprintf(" transform=\"rotate(%.2f %.2f %.2f)\"", -rotation, tx, ty)
+	}
+	fmt.Fprintf(p.dest, "<text x=\"%.2f\" y=\"%.2f\" font-family=\"%s\" font-size=\"%.2f\" text-anchor=\"%s\" fill=\"%s\" fill-opacity=\"%.2f\"%s>%s</text>\n",
+		tx, ty, fontname(svgfonts, font), p.w(size), anchor, attresc(textcolor(color)), opacityfrac(opacity), transform, xmlesc(xmlunesc(s)))
+}
+
+// svganchor converts deck alignment to SVG text anchors
+func svganchor(align string) string {
+	switch align {
+	case "c", "center", "middle", "mid":
+		return "middle"
+	case "e", "end", "r", "right":
+		return "end"
+	}
+	return "start"
+}
+
+// attrmap escapes attribute values
+var attrmap = strings.NewReplacer(
+	"&", "&amp;",
+	"<", "&lt;",
+	">", "&gt;",
+	"\"", "&quot;",
+	"'", "&#39;")
+
+// attresc escapes attribute values, which may already be XML escaped
+func attresc(s string) string {
+	return attrmap.Replace(xmlunesc(s))
+}
+
+// x converts a percentage to a horizontal coordinate
+func (p *SVG) x(v float64) float64 {
+	return (v / 100) * p.Width
+}
+
+// y converts a percentage to a vertical coordinate, with the origin at the top
+func (p *SVG) y(v float64) float64 {
+	return p.Height - (v/100)*p.Height
+}
+
+// w converts a percentage to a size relative to the width
+func (p *SVG) w(v float64) float64 {
+	return (v / 100) * p.Width
+}
+
+// h converts a percentage to a size relative to the height
+func (p *SVG) h(v float64) float64 {
+	return (v / 100) * p.Height
+}
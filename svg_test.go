@@ -0,0 +1,38 @@
+package dchart2
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSVGColorEscaped(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewSVG(&buf, 0, 0)
+	color := `red"/><script>alert(1)</script><rect fill="blue`
+	p.StartSlide(color)
+	p.Line(10, 10, 50, 50, 1, color)
+	p.Circle(50, 50, 5, color)
+	p.Rect(50, 50, 5, 5, color)
+	p.Polygon([]float64{10, 20, 30}, []float64{10, 20, 10}, color)
+	p.Arc(50, 50, 20, 20, 2, 0, 90, color)
+	p.Text(50, 50, "text", "sans", 2, color)
+	p.EndSlide()
+	if s := buf.String(); strings.Contains(s, "<script>") || strings.Contains(s, `red"`) {
+		t.Errorf("color not escaped:\n%s", s)
+	}
+}
+
+func TestSVGChart(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewSVG(&buf, 0, 0)
+	c := readchart(t, testdata)
+	p.StartSlide()
+	if err := c.Bar(p, 2); err != nil {
+		t.Fatal(err)
+	}
+	p.EndSlide()
+	if n := strings.Count(buf.String(), "<line"); n != 3 {
+		t.Errorf("got %d lines, want 3", n)
+	}
+}
@@ -0,0 +1,358 @@
+package dchart2
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// Terminal renders charts as text, for viewing in a terminal.
+// Bars and other filled areas are drawn with Unicode block characters,
+// lines, dots and outlines with braille characters (2x4 dots per character cell),
+// optionally in color using ANSI escape sequences.
+// The percentage coordinates are scaled to the number of columns and rows.
+type Terminal struct {
+	Cols  int
+	Rows  int
+	Color bool
+	dest  io.Writer
+	cells []termcell
+}
+
+// termcell is a character cell: text or a block character, or braille dots
+type termcell struct {
+	r     rune
+	dots  uint8
+	text  bool
+	color string
+}
+
+// braille dot bits, indexed by [row][column] within a cell
+var brailledots = [4][2]uint8{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+// partial blocks, in eighths
+var (
+	lowerblocks = []rune(" ▁▂▃▄▅▆▇█")
+	leftblocks  = []rune(" ▏▎▍▌▋▊▉█")
+)
+
+// NewTerminal makes a terminal renderer with the specified size in character cells
+// (default 80x24), using ANSI colors if color is true
+func NewTerminal(w io.Writer, cols, rows int, color bool) *Terminal {
+	if cols <= 0 {
+		cols = 80
+	}
+	if rows <= 0 {
+		rows = 24
+	}
+	return &Terminal{Cols: cols, Rows: rows, Color: color, dest: w}
+}
+
+// StartDeck begins the deck
+func (t *Terminal) StartDeck() {}
+
+// EndDeck ends the deck
+func (t *Terminal) EndDeck() {}
+
+// StartSlide begins a screen; the background color is not used
+func (t *Terminal) StartSlide(colors ...string) {
+	t.cells = make([]termcell, t.Cols*t.Rows)
+}
+
+// EndSlide writes the screen, omitting blank lines at the top and bottom
+func (t *Terminal) EndSlide() {
+	lines := make([]string, t.Rows)
+	first, last := t.Rows, -1
+	for row := 0; row < t.Rows; row++ {
+		lines[row] = t.line(row)
+		if len(lines[row]) > 0 {
+			if row < first {
+				first = row
+			}
+			last = row
+		}
+	}
+	w := bufio.NewWriter(t.dest)
+	for row := first; row <= last; row++ {
+		fmt.Fprintln(w, lines[row])
+	}
+	w.Flush()
+}
+
+// line returns a row of cells as text, with trailing spaces removed
+func (t *Terminal) line(row int) string {
+	var b strings.Builder
+	color := ""
+	for _, c := range t.cells[row*t.Cols : (row+1)*t.Cols] {
+		r := c.r
+		if r == 0 && c.dots != 0 {
+			r = 0x2800 + rune(c.dots)
+		}
+		if r == 0 {
+			r = ' '
+		}
+		if t.Color && r != ' ' && c.color != color {
+			b.WriteString(ansicolor(c.color))
+			color = c.color
+		}
+		b.WriteRune(r)
+	}
+	s := strings.TrimRight(b.String(), " ")
+	if t.Color && len(color) > 0 {
+		s += "\x1b[0m"
+	}
+	return s
+}
+
+// ansicolor returns the escape sequence that sets the (24-bit) foreground color
+func ansicolor(color string) string {
+	if len(color) == 0 {
+		return "\x1b[0m"
+	}
+	c := parsecolor(color, 1)
+	return fmt.Sprintf("\x1b[38;2;%d;%d;%dm", c.R, c.G, c.B)
+}
+
+// Line makes a line; thick horizontal and vertical lines (bars) are drawn as blocks
+func (t *Terminal) Line(x1, y1, x2, y2, size float64, color string, opacity ...float64) {
+	color = shapecolor(color)
+	ax, ay, bx, by := t.dx(x1), t.dy(y1), t.dx(x2), t.dy(y2)
+	thick := t.dw(size)
+	switch {
+	case thick >= 2 && ax == bx:
+		t.block(ax-thick/2, math.Min(ay, by), ax+thick/2, math.Max(ay, by), color, opacityfrac(opacity))
+	case thick >= 2 && ay == by:
+		t.block(math.Min(ax, bx), ay-thick/2, math.Max(ax, bx), ay+thick/2, color, opacityfrac(opacity))
+	default:
+		t.dotline(ax, ay, bx, by, color)
+	}
+}
+
+// Circle makes a circle, with the specified diameter
+func (t *Terminal) Circle(x, y, w float64, color string, opacity ...float64) {
+	cx, cy, r := t.dx(x), t.dy(y), t.dw(w)/2
+	t.dotfill(cx-r, cy-r, cx+r, cy+r, color, func(px, py float64) bool {
+		return math.Hypot(px-cx, py-cy) <= r
+	})
+	t.dot(cx, cy, color)
+}
+
+// Rect makes a rectangle centered at (x,y)
+func (t *Terminal) Rect(x, y, w, h float64, color string, opacity ...float64) {
+	cx, cy, hw, hh := t.dx(x), t.dy(y), t.dw(w)/2, t.dh(h)/2
+	t.block(cx-hw, cy-hh, cx+hw, cy+hh, shapecolor(color), opacityfrac(opacity))
+}
+
+// Polygon makes a filled polygon
+func (t *Terminal) Polygon(x, y []float64, color string, opacity ...float64) {
+	n := len(x)
+	if len(y) < n {
+		n = len(y)
+	}
+	if n < 3 {
+		return
+	}
+	px := make([]float64, n)
+	py := make([]float64, n)
+	minx, miny, maxx, maxy := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for i := 0; i < n; i++ {
+		px[i], py[i] = t.dx(x[i]), t.dy(y[i])
+		minx, maxx = math.Min(minx, px[i]), math.Max(maxx, px[i])
+		miny, maxy = math.Min(miny, py[i]), math.Max(maxy, py[i])
+	}
+	t.dotfill(minx, miny, maxx, maxy, color, func(x, y float64) bool {
+		return inpolygon(px, py, x, y)
+	})
+}
+
+// Arc makes an arc of the specified width (diameter) and size (stroke width),
+// from angle a1 to a2 (degrees, counterclockwise from 3 o'clock)
+func (t *Terminal) Arc(x, y, w, h, size, a1, a2 float64, color string, opacity ...float64) {
+	if !finite(a1) || !finite(a2) {
+		return
+	}
+	cx, cy, r, hs := t.dx(x), t.dy(y), t.dw(w)/2, math.Max(t.dw(size)/2, 0.5)
+	outer := r + hs
+	t.dotfill(cx-outer, cy-outer, cx+outer, cy+outer, color, func(px, py float64) bool {
+		d := math.Hypot(px-cx, py-cy)
+		if d < r-hs || d > outer {
+			return false
+		}
+		a := math.Atan2(cy-py, px-cx) * 180 / math.Pi
+		for a < a1 {
+			a += 360
+		}
+		return a <= a2
+	})
+}
+
+// Text places left-aligned text
+func (t *Terminal) Text(x, y float64, s, font string, size float64, color string, opacity ...float64) {
+	t.text(x, y, s, "start", color)
+}
+
+// TextMid places centered text
+func (t *Terminal) TextMid(x, y float64, s, font string, size float64, color string, opacity ...float64) {
+	t.text(x, y, s, "middle", color)
+}
+
+// TextEnd places right-aligned text
+func (t *Terminal) TextEnd(x, y float64, s, font string, size float64, color string, opacity ...float64) {
+	t.text(x, y, s, "end", color)
+}
+
+// TextRotate places text; text is not rotated in a terminal, only aligned
+func (t *Terminal) TextRotate(x, y float64, s, align, font string, rotation, size float64, color string, opacity ...float64) {
+	t.text(x, y, s, svganchor(align), color)
+}
+
+// text writes text into the cells, on the row containing y
+func (t *Terminal) text(x, y float64, s, anchor, color string) {
+	s = xmlunesc(s)
+	n := utf8.RuneCountInString(s)
+	col := int(math.Round(t.dx(x) / 2))
+	switch anchor {
+	case "middle":
+		col -= n / 2
+	case "end":
+		col -= n
+	}
+	row := int(t.dy(y) / 4)
+	if row < 0 || row >= t.Rows {
+		return
+	}
+	for _, r := range s {
+		if col >= 0 && col < t.Cols {
+			t.cells[row*t.Cols+col] = termcell{r: r, text: true, color: textcolor(color)}
+		}
+		col++
+	}
+}
+
+// block fills the area between dot coordinates with block characters,
+// using partial blocks at the top and right edges. Translucent areas are shaded,
+// without covering what is already drawn.
+func (t *Terminal) block(x1, y1, x2, y2 float64, color string, alpha float64) {
+	if !finite(x1) || !finite(y1) || !finite(x2) || !finite(y2) {
+		return
+	}
+	for row := int(y1 / 4); row <= int(y2/4) && row < t.Rows; row++ {
+		if row < 0 {
+			continue
+		}
+		fy := overlap(y1/4, y2/4, float64(row))
+		for col := int(x1 / 2); col <= int(x2/2) && col < t.Cols; col++ {
+			if col < 0 {
+				continue
+			}
+			fx := overlap(x1/2, x2/2, float64(col))
+			c := &t.cells[row*t.Cols+col]
+			if c.text || fx*fy == 0 {
+				continue
+			}
+			if alpha < 0.5 {
+				if c.r == 0 && c.dots == 0 && fx*fy >= 0.5 {
+					*c = termcell{r: '░', color: color}
+				}
+				continue
+			}
+			var r rune
+			switch {
+			case fx >= 1 && fy >= 1:
+				r = '█'
+			case fy < 1 && y2/4 >= float64(row+1): // top edge, covered from the bottom
+				if fx >= 0.5 {
+					r = lowerblocks[int(math.Round(fy*8))]
+				}
+			case fy >= 1 && x1/2 <= float64(col): // right edge, covered from the left
+				r = leftblocks[int(math.Round(fx*8))]
+			case fx*fy >= 0.5:
+				r = '█'
+			}
+			if r != 0 && r != ' ' {
+				*c = termcell{r: r, color: color}
+			}
+		}
+	}
+}
+
+// overlap returns the fraction of the cell [n, n+1) covered by [a, b]
+func overlap(a, b, n float64) float64 {
+	return math.Max(0, math.Min(b, n+1)-math.Max(a, n))
+}
+
+// dotline draws a line of braille dots
+func (t *Terminal) dotline(x1, y1, x2, y2 float64, color string) {
+	steps := int(math.Max(math.Abs(x2-x1), math.Abs(y2-y1))) + 1
+	for i := 0; i <= steps; i++ {
+		f := float64(i) / float64(steps)
+		t.dot(x1+(x2-x1)*f, y1+(y2-y1)*f, color)
+	}
+}
+
+// dotfill sets the braille dots within the bounds for which inside is true
+func (t *Terminal) dotfill(x1, y1, x2, y2 float64, color string, inside func(x, y float64) bool) {
+	if !finite(x1) || !finite(y1) || !finite(x2) || !finite(y2) {
+		return
+	}
+	for y := math.Floor(y1); y <= y2; y++ {
+		for x := math.Floor(x1); x <= x2; x++ {
+			if inside(x+0.5, y+0.5) {
+				t.dot(x, y, color)
+			}
+		}
+	}
+}
+
+// dot sets a braille dot, unless the cell holds text or a block
+func (t *Terminal) dot(x, y float64, color string) {
+	if !finite(x) || !finite(y) || x < 0 || y < 0 { // before converting to int
+		return
+	}
+	dx, dy := int(x), int(y)
+	col, row := dx/2, dy/4
+	if col >= t.Cols || row >= t.Rows {
+		return
+	}
+	c := &t.cells[row*t.Cols+col]
+	if c.text || (c.r != 0 && c.r != '░') {
+		return
+	}
+	c.r = 0
+	c.dots |= brailledots[dy%4][dx%2]
+	c.color = shapecolor(color)
+}
+
+// inpolygon reports whether (x,y) is inside the polygon (even-odd rule)
+func inpolygon(px, py []float64, x, y float64) bool {
+	in := false
+	for i, j := 0, len(px)-1; i < len(px); j, i = i, i+1 {
+		if (py[i] > y) != (py[j] > y) && x < (px[j]-px[i])*(y-py[i])/(py[j]-py[i])+px[i] {
+			in = !in
+		}
+	}
+	return in
+}
+
+// dx converts a percentage to a horizontal dot coordinate
+func (t *Terminal) dx(v float64) float64 {
+	return (v / 100) * float64(t.Cols*2)
+}
+
+// dy converts a percentage to a vertical dot coordinate, with the origin at the top
+func (t *Terminal) dy(v float64) float64 {
+	return (1 - v/100) * float64(t.Rows*4)
+}
+
+// dw converts a percentage to a size in dots, relative to the width
+func (t *Terminal) dw(v float64) float64 {
+	return (v / 100) * float64(t.Cols*2)
+}
+
+// dh converts a percentage to a size in dots, relative to the height
+func (t *Terminal) dh(v float64) float64 {
+	return (v / 100) * float64(t.Rows*4)
+}
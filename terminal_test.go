@@ -0,0 +1,45 @@
+package dchart2
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestTerminalOnePoint(t *testing.T) {
+	var buf bytes.Buffer
+	term := NewTerminal(&buf, 80, 24, false)
+	c := readchart(t, "a\t5\n")
+	term.StartSlide()
+	if err := c.Scatter(term, 1); err != nil {
+		t.Fatal(err)
+	}
+	term.EndSlide()
+	if strings.TrimSpace(buf.String()) == "" {
+		t.Error("the point is not drawn")
+	}
+}
+
+func TestTerminalNotFinite(t *testing.T) {
+	var buf bytes.Buffer
+	term := NewTerminal(&buf, 80, 24, false)
+	nan, inf := math.NaN(), math.Inf(1)
+	term.StartSlide()
+	term.Circle(nan, 50, 2, "red")
+	term.Circle(50, inf, 2, "red")
+	term.Line(nan, 10, 50, 50, 0.2, "red")
+	term.Rect(50, 50, inf, 10, "red")
+	term.Arc(50, 50, 20, 20, 2, 0, inf, "red")
+	term.Arc(50, 50, 20, 20, 2, nan, 90, "red")
+	term.EndSlide()
+}
+
+func TestTerminalZeroSumDonut(t *testing.T) {
+	var buf bytes.Buffer
+	term := NewTerminal(&buf, 80, 24, false)
+	c := readchart(t, "a\t5\nb\t-5\n")
+	term.StartSlide()
+	c.Donut(term, 40, 5, true, false)
+	term.EndSlide()
+}
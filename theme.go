@@ -0,0 +1,221 @@
+package dchart2
+
+import "strings"
+
+// Theme is a set of chart colors: the background, data, label and value colors,
+// the colors of titles, lines (such as the axes of slope charts), grid lines,
+// and guides (minor grid lines, leaders and spokes), the color of text on the
+// background, and of text on solid data colors.
+// Up and Down are the colors of rising and falling prices in financial charts.
+// Series is the palette of data series, and Std the palette of the standard
+// colors of data values (the data color "std").
+type Theme struct {
+	Background string
+	Data       string
+	Label      string
+	Value      string
+	Title      string
+	Line       string
+	Grid       string
+	Guide      string
+	Text       string
+	Contrast   string
+	Up         string
+	Down       string
+	Series     []string
+	Std        []string
+}
+
+// palettes are the named color palettes: the categorical palettes tableau10, okabe-ito,
+// and the ColorBrewer set1, set2, set3, dark2, paired, pastel1 and accent;
+// the sequential palettes (from dark to light) viridis, magma, plasma,
+// and the ColorBrewer blues, greens, greys, oranges, purples, reds, ylgnbu and ylorrd;
+// and the ColorBrewer diverging palettes rdbu, brbg and spectral.
+var palettes = map[string][]string{
+	"tableau10": {
+		"rgb(78,121,167)",
+		"rgb(242,142,43)",
+		"rgb(225,87,89)",
+		"rgb(118,183,178)",
+		"rgb(89,161,79)",
+		"rgb(237,201,72)",
+		"rgb(176,122,161)",
+		"rgb(255,157,167)",
+		"rgb(156,117,95)",
+		"rgb(186,176,172)",
+	},
+	"okabe-ito": {"rgb(230,159,0)", "rgb(86,180,233)", "rgb(0,158,115)", "rgb(240,228,66)", "rgb(0,114,178)", "rgb(213,94,0)", "rgb(204,121,167)", "rgb(0,0,0)"},
+	"set1":      {"rgb(228,26,28)", "rgb(55,126,184)", "rgb(77,175,74)", "rgb(152,78,163)", "rgb(255,127,0)", "rgb(255,255,51)", "rgb(166,86,40)", "rgb(247,129,191)", "rgb(153,153,153)"},
+	"set2":      {"rgb(102,194,165)", "rgb(252,141,98)", "rgb(141,160,203)", "rgb(231,138,195)", "rgb(166,216,84)", "rgb(255,217,47)", "rgb(229,196,148)", "rgb(179,179,179)"},
+	"set3":      {"rgb(141,211,199)", "rgb(255,255,179)", "rgb(190,186,218)", "rgb(251,128,114)", "rgb(128,177,211)", "rgb(253,180,98)", "rgb(179,222,105)", "rgb(252,205,229)", "rgb(217,217,217)", "rgb(188,128,189)", "rgb(204,235,197)", "rgb(255,237,111)"},
+	"dark2":     {"rgb(27,158,119)", "rgb(217,95,2)", "rgb(117,112,179)", "rgb(231,41,138)", "rgb(102,166,30)", "rgb(230,171,2)", "rgb(166,118,29)", "rgb(102,102,102)"},
+	"paired":    {"rgb(166,206,227)", "rgb(31,120,180)", "rgb(178,223,138)", "rgb(51,160,44)", "rgb(251,154,153)", "rgb(227,26,28)", "rgb(253,191,111)", "rgb(255,127,0)", "rgb(202,178,214)", "rgb(106,61,154)", "rgb(255,255,153)", "rgb(177,89,40)"},
+	"pastel1":   {"rgb(251,180,174)", "rgb(179,205,227)", "rgb(204,235,197)", "rgb(222,203,228)", "rgb(254,217,166)", "rgb(255,255,204)", "rgb(229,216,189)", "rgb(253,218,236)", "rgb(242,242,242)"},
+	"accent":    {"rgb(127,201,127)", "rgb(190,174,212)", "rgb(253,192,134)", "rgb(255,255,153)", "rgb(56,108,176)", "rgb(240,2,127)", "rgb(191,91,23)", "rgb(102,102,102)"},
+	"viridis":   {"rgb(68,1,84)", "rgb(68,57,131)", "rgb(49,104,142)", "rgb(33,145,140)", "rgb(53,183,121)", "rgb(144,215,67)", "rgb(253,231,37)"},
+	"magma":     {"rgb(0,0,4)", "rgb(45,17,96)", "rgb(114,31,129)", "rgb(182,54,121)", "rgb(241,96,93)", "rgb(254,175,119)", "rgb(252,253,191)"},
+	"plasma":    {"rgb(13,8,135)", "rgb(92,1,166)", "rgb(156,23,158)", "rgb(204,71,120)", "rgb(237,121,83)", "rgb(253,180,47)", "rgb(240,249,33)"},
+	"blues":     {"rgb(8,69,148)", "rgb(33,113,181)", "rgb(66,146,198)", "rgb(107,174,214)", "rgb(158,202,225)", "rgb(198,219,239)", "rgb(239,243,255)"},
+	"greens":    {"rgb(0,90,50)", "rgb(35,139,69)", "rgb(65,171,93)", "rgb(116,196,118)", "rgb(161,217,155)", "rgb(199,233,192)", "rgb(237,248,233)"},
+	"greys":     {"rgb(37,37,37)", "rgb(82,82,82)", "rgb(115,115,115)", "rgb(150,150,150)", "rgb(189,189,189)", "rgb(217,217,217)", "rgb(247,247,247)"},
+	"oranges":   {"rgb(140,45,4)", "rgb(217,72,1)", "rgb(241,105,19)", "rgb(253,141,60)", "rgb(253,174,107)", "rgb(253,208,162)", "rgb(254,237,222)"},
+	"purples":   {"rgb(74,20,134)", "rgb(106,81,163)", "rgb(128,125,186)", "rgb(158,154,200)", "rgb(188,189,220)", "rgb(218,218,235)", "rgb(242,240,247)"},
+	"reds":      {"rgb(153,0,13)", "rgb(203,24,29)", "rgb(239,59,44)", "rgb(251,106,74)", "rgb(252,146,114)", "rgb(252,187,161)", "rgb(254,229,217)"},
+	"ylgnbu":    {"rgb(12,44,132)", "rgb(34,94,168)", "rgb(29,145,192)", "rgb(65,182,196)", "rgb(127,205,187)", "rgb(199,233,180)", "rgb(255,255,204)"},
+	"ylorrd":    {"rgb(177,0,38)", "rgb(227,26,28)", "rgb(252,78,42)", "rgb(253,141,60)", "rgb(254,178,76)", "rgb(254,217,118)", "rgb(255,255,178)"},
+	"rdbu":      {"rgb(178,24,43)", "rgb(239,138,98)", "rgb(253,219,199)", "rgb(247,247,247)", "rgb(209,229,240)", "rgb(103,169,207)", "rgb(33,102,172)"},
+	"brbg":      {"rgb(140,81,10)", "rgb(216,179,101)", "rgb(246,232,195)", "rgb(245,245,245)", "rgb(199,234,229)", "rgb(90,180,172)", "rgb(1,102,94)"},
+	"spectral":  {"rgb(213,62,79)", "rgb(252,141,89)", "rgb(254,224,139)", "rgb(255,255,191)", "rgb(230,245,152)", "rgb(153,213,148)", "rgb(50,136,189)"},
+}
+
+// lighttheme is the default theme: dark text on white
+var lighttheme = Theme{
+	Background: "white",
+	Data:       "lightsteelblue",
+	Label:      "rgb(75,75,75)",
+	Value:      "rgb(128,0,0)",
+	Title:      "black",
+	Line:       "black",
+	Grid:       "gray",
+	Guide:      "lightgray",
+	Text:       "black",
+	Contrast:   "white",
+	Up:         "rgb(38,166,91)",
+	Down:       "rgb(214,39,40)",
+	Series:     palettes["tableau10"],
+	Std:        palettes["blues"],
+}
+
+// themes are the named themes
+var themes = map[string]Theme{
+	"light": lighttheme,
+	"dark": {
+		Background: "rgb(32,34,38)",
+		Data:       "rgb(120,160,210)",
+		Label:      "rgb(200,200,200)",
+		Value:      "rgb(250,170,120)",
+		Title:      "white",
+		Line:       "rgb(220,220,220)",
+		Grid:       "rgb(120,120,120)",
+		Guide:      "rgb(70,72,76)",
+		Text:       "white",
+		Contrast:   "black",
+		Up:         "rgb(80,200,120)",
+		Down:       "rgb(240,90,90)",
+		Series:     palettes["set2"],
+		Std:        palettes["set2"],
+	},
+	"print": {
+		Background: "white",
+		Data:       "rgb(150,150,150)",
+		Label:      "black",
+		Value:      "black",
+		Title:      "black",
+		Line:       "black",
+		Grid:       "rgb(160,160,160)",
+		Guide:      "rgb(210,210,210)",
+		Text:       "black",
+		Contrast:   "white",
+		Up:         "rgb(150,150,150)",
+		Down:       "black",
+		Series:     []string{"rgb(37,37,37)", "rgb(150,150,150)", "rgb(82,82,82)", "rgb(189,189,189)", "rgb(115,115,115)", "rgb(217,217,217)"},
+		Std:        palettes["greys"],
+	},
+	"high-contrast": {
+		Background: "black",
+		Data:       "yellow",
+		Label:      "white",
+		Value:      "cyan",
+		Title:      "white",
+		Line:       "white",
+		Grid:       "rgb(200,200,200)",
+		Guide:      "rgb(128,128,128)",
+		Text:       "white",
+		Contrast:   "black",
+		Up:         "rgb(0,255,0)",
+		Down:       "rgb(255,64,64)",
+		Series:     []string{"yellow", "cyan", "magenta", "rgb(0,255,0)", "rgb(255,128,0)", "white"},
+		Std:        []string{"yellow", "cyan", "magenta", "rgb(0,255,0)", "rgb(255,128,0)", "white"},
+	},
+}
+
+// NewTheme returns the theme with the specified name: light (the default), dark, print or high-contrast
+func NewTheme(name string) (Theme, error) {
+	if len(name) == 0 {
+		name = "light"
+	}
+	t, ok := themes[strings.ToLower(name)]
+	if !ok {
+		return Theme{}, &ArgumentError{Name: name, Reason: "unknown theme"}
+	}
+	t.Series = append([]string(nil), t.Series...)
+	t.Std = append([]string(nil), t.Std...)
+	return t, nil
+}
+
+// NewPalette returns the colors of the named palette
+func NewPalette(name string) ([]string, error) {
+	p, ok := palettes[strings.ToLower(name)]
+	if !ok {
+		return nil, &ArgumentError{Name: name, Reason: "unknown palette"}
+	}
+	return append([]string(nil), p...), nil
+}
+
+// SetTheme sets the data, label and value colors of the chart to those of the theme,
+// and uses the theme for the other colors of the chart
+func (c *ChartBox) SetTheme(t Theme) {
+	c.DataColor = t.Data
+	c.LabelColor = t.Label
+	c.ValueColor = t.Value
+	c.Theme = &t
+}
+
+// theme returns the theme of the chart, by default the light theme
+func (c *ChartBox) theme() *Theme {
+	if c.Theme == nil {
+		return &lighttheme
+	}
+	return c.Theme
+}
+
+// palette returns the colors of the named palette, if any;
+// "std" names the standard palette of the chart's theme
+func (c *ChartBox) palette(name string) []string {
+	if name == "std" {
+		return c.theme().Std
+	}
+	return palettes[strings.ToLower(name)]
+}
+
+// theme returns the theme of the settings, with its palettes replaced by the named palette,
+// and its data, label, value, up and down colors by those specified
+func (a Attributes) theme() (Theme, error) {
+	t, err := NewTheme(a.Theme)
+	if err != nil {
+		return t, err
+	}
+	if len(a.Palette) > 0 {
+		p, err := NewPalette(a.Palette)
+		if err != nil {
+			return t, err
+		}
+		t.Series, t.Std = p, p
+	}
+	if len(a.DataColor) > 0 {
+		t.Data = a.DataColor
+	}
+	if len(a.LabelColor) > 0 {
+		t.Label = a.LabelColor
+	}
+	if len(a.ValueColor) > 0 {
+		t.Value = a.ValueColor
+	}
+	if len(a.UpColor) > 0 {
+		t.Up = a.UpColor
+	}
+	if len(a.DownColor) > 0 {
+		t.Down = a.DownColor
+	}
+	return t, nil
+}
@@ -0,0 +1,31 @@
+package dchart2
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewTheme(t *testing.T) {
+	for _, name := range []string{"", "light", "dark", "print", "high-contrast"} {
+		if _, err := NewTheme(name); err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+	_, err := NewTheme("plaid")
+	var aerr *ArgumentError
+	if !errors.As(err, &aerr) {
+		t.Errorf("an unknown theme is %T, want *ArgumentError", err)
+	}
+}
+
+func TestNewPalette(t *testing.T) {
+	p, err := NewPalette("viridis")
+	if err != nil || len(p) == 0 {
+		t.Errorf("viridis: %v %v", p, err)
+	}
+	_, err = NewPalette("plaid")
+	var aerr *ArgumentError
+	if !errors.As(err, &aerr) {
+		t.Errorf("an unknown palette is %T, want *ArgumentError", err)
+	}
+}